@@ -1,27 +1,71 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
 	"time"
 )
 
+const (
+	stateFileName   = ".run-mcps-state.json"
+	defaultProxyCmd = "pnpm dlx mcp-proxy"
+)
+
+// runState is what -detach records so a later -stop can find the servers.
+type runState struct {
+	StartedAt string      `json:"started_at"`
+	Procs     []procState `json:"procs"`
+}
+
+type procState struct {
+	Name string `json:"name"`
+	PID  int    `json:"pid"`
+	// StartTime is the operating system's record of when the process
+	// started, as read by processStartTime. It tells the server apart from
+	// a later process that reuses its PID.
+	StartTime  string `json:"start_time,omitempty"`
+	Port       int    `json:"port"`
+	StopSignal string `json:"stop_signal,omitempty"`
+}
+
 type procSpec struct {
 	name string
+	port int
 	cmd  []string
 	env  []string
+	// envUnset names variables removed from the inherited environment
+	// before env is applied.
+	envUnset []string
+	// stopSignal is the stopSignals name of the signal sent on shutdown.
+	stopSignal string
+	// readyPattern, when set, marks the server ready as soon as a line of
+	// its output matches, even before its port accepts connections.
+	readyPattern *regexp.Regexp
 }
 
+// serverNames lists every server run-mcps knows how to launch.
+var serverNames = []string{"tavily", "context7", "playwright", "github", "test-verifier", "test-registrar", "agentation", "storybook"}
+
 func main() {
 	defaultStorybookPort := portFromEnv("STORYBOOK_PORT", 7016)
 	defaultAgentationPort := portFromEnv("AGENTATION_MCP_PORT", 7017)
@@ -33,9 +77,47 @@ func main() {
 	agentationPort := flag.Int("agentation-port", defaultAgentationPort, "Port for Agentation MCP proxy (optional, defaults to AGENTATION_MCP_PORT or 7017)")
 	storybookDir := flag.String("storybook-dir", os.Getenv("STORYBOOK_DIR"), "Path to project root with Storybook + @storybook/addon-mcp (optional)")
 	storybookPort := flag.Int("storybook-port", defaultStorybookPort, "Port for Storybook MCP HTTP server (optional, defaults to STORYBOOK_PORT or 7016)")
+	portOverrides := flag.String("ports", "", "Explicit per-server ports as name=port pairs, e.g. tavily=8001,github=9000 (overrides the base-port offsets)")
+	detach := flag.Bool("detach", false, "Start all servers, record their PIDs in the state file, and exit without waiting")
+	stop := flag.Bool("stop", false, "Stop the servers recorded in the state file by an earlier -detach run, then exit")
+	stateFile := flag.String("state-file", "", "Path of the -detach/-stop state file (default .run-mcps-state.json in the repo root)")
+	startupRetries := flag.Int("startup-retries", 0, "Times to retry starting a server whose process fails to launch, with exponential backoff starting at 1s")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running the other servers when one still fails to launch after its retries (default: stop everything and exit)")
+	proxyCmd := flag.String("proxy-cmd", defaultProxyCmd, "Command (with args, space-separated) used to expose stdio servers over HTTP, e.g. a locally built mcp-proxy binary")
+	list := flag.Bool("list", false, "Print the servers that would be started (name, port, command, env keys) and exit without starting them; JSON with -log-format json")
+	logFormat := flag.String("log-format", "text", "Format of server lifecycle events: text or json (one object per line on stderr)")
+	var serverEnv, serverUnsetEnv listFlag
+	flag.Var(&serverEnv, "server-env", "Set a variable for one server as name:KEY=VALUE, overriding the inherited and built-in value (repeatable)")
+	flag.Var(&serverUnsetEnv, "server-unset-env", "Remove an inherited variable from one server's environment as name:KEY (repeatable)")
+	stopSignal := flag.String("stop-signal", "INT", "Signal sent to every server on shutdown before it is killed: INT, TERM, HUP, QUIT or KILL")
+	var serverStopSignal listFlag
+	flag.Var(&serverStopSignal, "server-stop-signal", "Stop signal for one server as name:SIGNAL, overriding -stop-signal (repeatable)")
+	var serverReadyPattern listFlag
+	flag.Var(&serverReadyPattern, "server-ready-pattern", "Mark one server ready when a line of its stdout or stderr matches a Go regexp, as name:REGEX (repeatable; ignored with -detach)")
+	readyTimeout := flag.Duration("ready-timeout", 2*time.Minute, "How long to wait for each server to become ready before logging that it is not")
 	flag.Parse()
 
-	if *tavilyKey == "" || *githubToken == "" {
+	events, err := newEventLogger(*logFormat, os.Stderr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *stateFile == "" {
+		*stateFile = filepath.Join(resolveRepoRoot(), stateFileName)
+	}
+	if *stop {
+		if err := stopDetached(*stateFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *detach {
+		if err := checkStaleState(*stateFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if !*list && (*tavilyKey == "" || *githubToken == "") {
 		log.Println("Tavily:", *tavilyKey != "")
 		log.Println("GitHub:", *githubToken != "")
 		log.Fatal("Missing required keys. Set TAVILY_API_KEY and GITHUB_PERSONAL_ACCESS_TOKEN (or GITHUB_API_KEY) or pass flags.")
@@ -46,12 +128,54 @@ func main() {
 	if !isValidPort(*storybookPort) {
 		log.Fatalf("storybook port must be between 1 and 65535, got %d", *storybookPort)
 	}
+	overrides, err := parsePortOverrides(*portOverrides)
+	if err != nil {
+		log.Fatal(err)
+	}
+	envOverrides, err := parseServerEnv("-server-env", serverEnv, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	envUnsets, err := parseServerEnv("-server-unset-env", serverUnsetEnv, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defaultStopSignal, err := canonicalStopSignal(*stopSignal)
+	if err != nil {
+		log.Fatalf("invalid -stop-signal: %v", err)
+	}
+	stopSignalOverrides, err := parseServerStopSignals(serverStopSignal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	readyPatterns, err := parseServerReadyPatterns(serverReadyPattern)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *detach && len(readyPatterns) > 0 {
+		// Watching output needs pipes that would break once run-mcps exits.
+		log.Println("-server-ready-pattern is ignored with -detach")
+		readyPatterns = nil
+	}
+	ports := make(map[string]int, len(serverNames))
+	for _, name := range serverNames {
+		ports[name] = portFor(name, *basePort, *storybookPort, *agentationPort)
+		if port, ok := overrides[name]; ok {
+			ports[name] = port
+		}
+	}
+
+	proxy := strings.Fields(*proxyCmd)
+	if len(proxy) == 0 {
+		log.Fatal("-proxy-cmd must not be empty")
+	}
 
 	// Most MCPs are stdio-based and are exposed via mcp-proxy.
 	// Storybook (when enabled) runs as its own HTTP MCP endpoint.
 	githubPath := githubBinary()
 	if githubPath == "" {
-		log.Fatal("GitHub MCP binary not found. Build it and add to PATH or place it in ~/bin (github-mcp-server or github-mcp-server.exe).")
+		// Leave the bare name so the preflight check reports it with the others.
+		githubPath = githubBinaryName()
 	}
 	repoRoot := resolveRepoRoot()
 	testVerifierEnv := testVerifierEnv(repoRoot)
@@ -64,77 +188,150 @@ func main() {
 	specs := []procSpec{
 		{
 			name: "tavily",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *basePort), "--", "pnpm", "dlx", "tavily-mcp@latest"},
+			port: ports["tavily"],
+			cmd:  proxyCommand(proxy, *host, ports["tavily"], "pnpm", "dlx", "tavily-mcp@latest"),
 			env:  []string{"TAVILY_API_KEY=" + *tavilyKey},
 		},
 		{
 			name: "context7",
-			cmd:  context7Command(*host, *basePort+1, *context7Key),
+			port: ports["context7"],
+			cmd:  context7Command(proxy, *host, ports["context7"], *context7Key),
 			env:  nil,
 		},
 		{
 			name: "playwright",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *basePort+2), "--", "pnpm", "dlx", "@playwright/mcp@latest"},
+			port: ports["playwright"],
+			cmd:  proxyCommand(proxy, *host, ports["playwright"], "pnpm", "dlx", "@playwright/mcp@latest"),
 			env:  nil,
 		},
 		{
 			name: "github",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *basePort+3), "--", githubPath, "stdio"},
+			port: ports["github"],
+			cmd:  proxyCommand(proxy, *host, ports["github"], githubPath, "stdio"),
 			env:  []string{"GITHUB_PERSONAL_ACCESS_TOKEN=" + *githubToken},
 		},
 		{
 			name: "test-verifier",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *basePort+4), "--", "go", "-C", testVerifierPath, "run", "."},
+			port: ports["test-verifier"],
+			cmd:  proxyCommand(proxy, *host, ports["test-verifier"], "go", "-C", testVerifierPath, "run", "."),
 			env:  testVerifierEnv,
 		},
 		{
 			name: "test-registrar",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *basePort+5), "--", "go", "-C", testRegistrarPath, "run", "."},
+			port: ports["test-registrar"],
+			cmd:  proxyCommand(proxy, *host, ports["test-registrar"], "go", "-C", testRegistrarPath, "run", "."),
 			env:  testVerifierEnv,
 		},
 		{
 			name: "agentation",
-			cmd:  []string{"pnpm", "dlx", "mcp-proxy", "--host", *host, "--port", fmt.Sprintf("%d", *agentationPort), "--", "pnpm", "dlx", "agentation-mcp", "server", "--mcp-only"},
+			port: ports["agentation"],
+			cmd:  proxyCommand(proxy, *host, ports["agentation"], "pnpm", "dlx", "agentation-mcp", "server", "--mcp-only"),
 			env:  nil,
 		},
 	}
 	if *storybookDir != "" {
 		specs = append(specs, procSpec{
 			name: "storybook",
-			cmd:  storybookCommand(*storybookDir, *host, *storybookPort),
+			port: ports["storybook"],
+			cmd:  storybookCommand(*storybookDir, *host, ports["storybook"]),
 			env:  nil,
 		})
-	} else if storybookMCPAvailable(*host, *storybookPort) {
-		log.Printf("storybook external endpoint detected at http://%s:%d/mcp (not managed by run-mcps)", *host, *storybookPort)
+	} else if storybookMCPAvailable(*host, ports["storybook"]) {
+		log.Printf("storybook external endpoint detected at http://%s:%d/mcp (not managed by run-mcps)", *host, ports["storybook"])
 	} else {
 		log.Println("storybook disabled: set STORYBOOK_DIR or pass -storybook-dir to start Storybook MCP")
 	}
+	for i := range specs {
+		name := specs[i].name
+		// mergeEnv builds a new slice, leaving the env slice that
+		// test-verifier and test-registrar share untouched.
+		specs[i].env = mergeEnv(specs[i].env, envOverrides[name])
+		specs[i].envUnset = envUnsets[name]
+		specs[i].stopSignal = defaultStopSignal
+		if sig, ok := stopSignalOverrides[name]; ok {
+			specs[i].stopSignal = sig
+		}
+		specs[i].readyPattern = readyPatterns[name]
+	}
+
+	if *list {
+		if err := printPlan(os.Stdout, specs, *logFormat == "json", []string{*tavilyKey, *context7Key, *githubToken}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := checkPortConflicts(specs); err != nil {
+		log.Fatal(err)
+	}
+	if err := preflight(specs); err != nil {
+		log.Fatal(err)
+	}
 
 	procs := make([]*exec.Cmd, 0, len(specs))
+	started := make([]procSpec, 0, len(specs))
+	watchers := make([]*readyWatcher, 0, len(specs))
+	state := runState{StartedAt: time.Now().UTC().Format(time.RFC3339)}
 	for _, spec := range specs {
-		cmd := exec.Command(spec.cmd[0], spec.cmd[1:]...)
-		cmd.Env = append(os.Environ(), spec.env...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Start(); err != nil {
-			log.Fatalf("failed to start %s: %v", spec.name, err)
+		ready := newReadyWatcher(spec.readyPattern)
+		cmd, err := startWithRetries(events, spec, *startupRetries, ready)
+		if err != nil {
+			events.emit(serverEvent{Event: "start_failed", Server: spec.name, Port: spec.port, Error: err.Error()},
+				fmt.Sprintf("failed to start %s: %v", spec.name, err))
+			if *continueOnError {
+				continue
+			}
+			for _, cmd := range procs {
+				_ = cmd.Process.Kill()
+			}
+			os.Exit(1)
 		}
-		port := portFor(spec.name, *basePort, *storybookPort, *agentationPort)
+		port := spec.port
+		text := fmt.Sprintf("started %s on port %d (pid=%d)", spec.name, port, cmd.Process.Pid)
 		if spec.name == "storybook" {
-			log.Printf("started %s on port %d (pid=%d) (MCP endpoint: http://%s:%d/mcp)", spec.name, port, cmd.Process.Pid, *host, port)
-		} else {
-			log.Printf("started %s on port %d (pid=%d)", spec.name, port, cmd.Process.Pid)
+			text += fmt.Sprintf(" (MCP endpoint: http://%s:%d/mcp)", *host, port)
 		}
+		events.emit(serverEvent{Event: "start", Server: spec.name, Port: port, PID: cmd.Process.Pid}, text)
 		procs = append(procs, cmd)
+		started = append(started, spec)
+		watchers = append(watchers, ready)
+		proc := procState{Name: spec.name, PID: cmd.Process.Pid, Port: spec.port, StopSignal: spec.stopSignal}
+		if *detach {
+			if proc.StartTime, err = processStartTime(proc.PID); err != nil {
+				log.Printf("cannot record the start time of %s (pid=%d); -stop will not force-kill it: %v", spec.name, proc.PID, err)
+			}
+		}
+		state.Procs = append(state.Procs, proc)
+	}
+	if len(procs) == 0 {
+		log.Fatal("no servers could be started")
+	}
+
+	if *detach {
+		if err := writeState(*stateFile, state); err != nil {
+			log.Printf("failed to write state file, stopping servers: %v", err)
+			for _, cmd := range procs {
+				_ = cmd.Process.Kill()
+			}
+			os.Exit(1)
+		}
+		log.Printf("detached; stop the servers with: run-mcps -stop -state-file %s", *stateFile)
+		return
+	}
+
+	var shuttingDown atomic.Bool
+	for i, cmd := range procs {
+		go watchProcess(events, started[i], cmd, *host, *readyTimeout, watchers[i], &shuttingDown)
 	}
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
-	log.Println("shutting down...")
+	shuttingDown.Store(true)
+	events.emit(serverEvent{Event: "shutdown"}, "shutting down...")
 
-	for _, cmd := range procs {
-		_ = cmd.Process.Signal(os.Interrupt)
+	for i, cmd := range procs {
+		_ = cmd.Process.Signal(stopSignals[started[i].stopSignal])
 	}
 	time.Sleep(2 * time.Second)
 	for _, cmd := range procs {
@@ -142,11 +339,400 @@ func main() {
 	}
 }
 
-func githubBinary() string {
-	name := "github-mcp-server"
+// plannedServer is one entry of the -list output.
+type plannedServer struct {
+	Name     string   `json:"name"`
+	Port     int      `json:"port"`
+	Command  []string `json:"command"`
+	EnvKeys  []string `json:"env_keys,omitempty"`
+	EnvUnset []string `json:"env_unset,omitempty"`
+}
+
+// printPlan writes the servers run-mcps would start. Env values are never
+// shown, and any secret that appears in a command line is redacted.
+func printPlan(w io.Writer, specs []procSpec, asJSON bool, secrets []string) error {
+	plan := make([]plannedServer, 0, len(specs))
+	for _, spec := range specs {
+		entry := plannedServer{Name: spec.name, Port: spec.port, EnvUnset: spec.envUnset}
+		for _, arg := range spec.cmd {
+			for _, secret := range secrets {
+				if secret != "" {
+					arg = strings.ReplaceAll(arg, secret, "[redacted]")
+				}
+			}
+			entry.Command = append(entry.Command, arg)
+		}
+		for _, kv := range spec.env {
+			key, _, _ := strings.Cut(kv, "=")
+			entry.EnvKeys = append(entry.EnvKeys, key)
+		}
+		plan = append(plan, entry)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPORT\tENV\tCOMMAND")
+	for _, entry := range plan {
+		keys := append([]string(nil), entry.EnvKeys...)
+		for _, key := range entry.EnvUnset {
+			keys = append(keys, "-"+key)
+		}
+		env := strings.Join(keys, ",")
+		if env == "" {
+			env = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", entry.Name, entry.Port, env, strings.Join(entry.Command, " "))
+	}
+	return tw.Flush()
+}
+
+// startWithRetries launches spec, retrying up to retries more times with
+// exponential backoff when the process cannot be started. A non-nil ready
+// watches the server's output for its ready pattern.
+func startWithRetries(events *eventLogger, spec procSpec, retries int, ready *readyWatcher) (*exec.Cmd, error) {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		cmd := exec.Command(spec.cmd[0], spec.cmd[1:]...)
+		cmd.Env = mergeEnv(unsetEnv(os.Environ(), spec.envUnset), spec.env)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if ready != nil {
+			cmd.Stdout = ready.writer(os.Stdout)
+			cmd.Stderr = ready.writer(os.Stderr)
+		}
+		err := cmd.Start()
+		if err == nil {
+			return cmd, nil
+		}
+		if attempt >= retries {
+			return nil, err
+		}
+		events.emit(serverEvent{Event: "retry", Server: spec.name, Port: spec.port, Error: err.Error()},
+			fmt.Sprintf("failed to start %s (attempt %d of %d), retrying in %s: %v", spec.name, attempt+1, retries+1, backoff, err))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// serverEvent is one lifecycle event in -log-format json output.
+type serverEvent struct {
+	Event  string `json:"event"`
+	Server string `json:"server,omitempty"`
+	Port   int    `json:"port,omitempty"`
+	PID    int    `json:"pid,omitempty"`
+	TS     string `json:"ts"`
+	Error  string `json:"error,omitempty"`
+	// Via says what marked a server ready: "port" or "pattern".
+	Via string `json:"via,omitempty"`
+}
+
+// eventLogger writes lifecycle events either as the usual log lines or as
+// JSON lines for tools that launch run-mcps.
+type eventLogger struct {
+	mu   sync.Mutex
+	json *json.Encoder
+}
+
+func newEventLogger(format string, w io.Writer) (*eventLogger, error) {
+	switch format {
+	case "text":
+		return &eventLogger{}, nil
+	case "json":
+		return &eventLogger{json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown -log-format %q (want text or json)", format)
+}
+
+// emit records ev, or logs text when the logger is in text mode.
+func (l *eventLogger) emit(ev serverEvent, text string) {
+	if l.json == nil {
+		log.Println(text)
+		return
+	}
+	ev.TS = time.Now().UTC().Format(time.RFC3339Nano)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_ = l.json.Encode(ev)
+}
+
+// watchProcess reports when a server starts accepting connections or
+// prints its ready pattern, when it is still not ready after readyTimeout,
+// and when it exits before run-mcps asked it to.
+func watchProcess(events *eventLogger, spec procSpec, cmd *exec.Cmd, host string, readyTimeout time.Duration, ready *readyWatcher, shuttingDown *atomic.Bool) {
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	pid := cmd.Process.Pid
+	addr := net.JoinHostPort(host, strconv.Itoa(spec.port))
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(readyTimeout)
+probe:
+	for {
+		select {
+		case err := <-exited:
+			reportExit(events, spec, pid, err, shuttingDown)
+			return
+		case <-deadline:
+			events.emit(serverEvent{Event: "ready_timeout", Server: spec.name, Port: spec.port, PID: pid},
+				fmt.Sprintf("%s not ready after %s (pid=%d)", spec.name, readyTimeout, pid))
+			break probe
+		case <-ready.done():
+			events.emit(serverEvent{Event: "ready", Server: spec.name, Port: spec.port, PID: pid, Via: "pattern"},
+				fmt.Sprintf("%s ready on port %d (pid=%d, output matched %q)", spec.name, spec.port, pid, spec.readyPattern))
+			break probe
+		case <-ticker.C:
+			conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+			if err != nil {
+				continue
+			}
+			conn.Close()
+			events.emit(serverEvent{Event: "ready", Server: spec.name, Port: spec.port, PID: pid, Via: "port"},
+				fmt.Sprintf("%s ready on port %d (pid=%d)", spec.name, spec.port, pid))
+			break probe
+		}
+	}
+	reportExit(events, spec, pid, <-exited, shuttingDown)
+}
+
+func reportExit(events *eventLogger, spec procSpec, pid int, err error, shuttingDown *atomic.Bool) {
+	if shuttingDown.Load() {
+		events.emit(serverEvent{Event: "stopped", Server: spec.name, Port: spec.port, PID: pid},
+			fmt.Sprintf("stopped %s (pid=%d)", spec.name, pid))
+		return
+	}
+	ev := serverEvent{Event: "crash", Server: spec.name, Port: spec.port, PID: pid}
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		ev.Error = "exited with status 0"
+	}
+	events.emit(ev, fmt.Sprintf("%s (pid=%d) exited unexpectedly: %s", spec.name, pid, ev.Error))
+}
+
+func writeState(path string, state runState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func readState(path string) (runState, error) {
+	var state runState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// checkStaleState refuses to detach over a state file whose processes are
+// still alive, and discards one whose processes have all exited. A PID now
+// used by a process with another start time does not count as alive.
+func checkStaleState(path string) error {
+	state, err := readState(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, p := range state.Procs {
+		switch status := recordedProcessStatus(p); status {
+		case procRunning, procUnverified:
+			hint := ""
+			if status == procUnverified {
+				hint = " (cannot verify that it is the recorded server; delete the state file if it is not)"
+			}
+			return fmt.Errorf("servers from a previous -detach run are still running (%s pid=%d)%s; stop them first with -stop -state-file %s", p.Name, p.PID, hint, path)
+		case procReused:
+			log.Printf("%s (pid=%d) exited and its PID now belongs to another process", p.Name, p.PID)
+		}
+	}
+	log.Printf("removing stale state file %s (no recorded process is running)", path)
+	return os.Remove(path)
+}
+
+// stopDetached signals every process in the state file, skipping ones that
+// already exited, and removes the file once they are gone.
+func stopDetached(path string) error {
+	state, err := readState(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no state file at %s; nothing to stop", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only processes whose start time matches the state file are signalled
+	// and then killed. One that cannot be verified, e.g. from a state file
+	// written before start times were recorded, only gets the stop signal.
+	var running []procState
+	for _, p := range state.Procs {
+		status := recordedProcessStatus(p)
+		switch status {
+		case procExited:
+			log.Printf("%s (pid=%d) is not running; skipping", p.Name, p.PID)
+			continue
+		case procReused:
+			log.Printf("%s (pid=%d) exited and its PID now belongs to another process; skipping", p.Name, p.PID)
+			continue
+		}
+		proc, err := os.FindProcess(p.PID)
+		if err != nil {
+			continue
+		}
+		// Windows cannot deliver signals other than kill to another process.
+		if runtime.GOOS == "windows" {
+			if status == procUnverified {
+				log.Printf("cannot verify that pid %d is still %s; not killing it", p.PID, p.Name)
+				continue
+			}
+			err = proc.Kill()
+		} else {
+			err = proc.Signal(recordedStopSignal(p.StopSignal))
+		}
+		if err != nil {
+			log.Printf("failed to stop %s (pid=%d): %v", p.Name, p.PID, err)
+			continue
+		}
+		log.Printf("stopping %s (pid=%d, port %d)", p.Name, p.PID, p.Port)
+		if status == procUnverified {
+			log.Printf("cannot verify that pid %d is still %s; it will not be force-killed", p.PID, p.Name)
+			continue
+		}
+		running = append(running, p)
+	}
+
+	if len(running) > 0 {
+		time.Sleep(2 * time.Second)
+		for _, p := range running {
+			// The PID may have been freed and reused while waiting.
+			if recordedProcessStatus(p) != procRunning {
+				continue
+			}
+			if proc, err := os.FindProcess(p.PID); err == nil {
+				_ = proc.Kill()
+			}
+		}
+	}
+	return os.Remove(path)
+}
+
+// procStatus is what became of a process recorded in the state file.
+type procStatus int
+
+const (
+	procExited procStatus = iota
+	// procRunning means the PID is alive and has the recorded start time.
+	procRunning
+	// procReused means the PID is alive but started at another time, so
+	// it is some other process.
+	procReused
+	// procUnverified means the PID is alive but its start time was not
+	// recorded or cannot be read.
+	procUnverified
+)
+
+func recordedProcessStatus(p procState) procStatus {
+	if !processAlive(p.PID) {
+		return procExited
+	}
+	if p.StartTime == "" {
+		return procUnverified
+	}
+	started, err := processStartTime(p.PID)
+	switch {
+	case err != nil && !processAlive(p.PID):
+		return procExited
+	case err != nil:
+		return procUnverified
+	case started != p.StartTime:
+		return procReused
+	}
+	return procRunning
+}
+
+// processStartTime returns when the operating system says process pid
+// started, in a form that only needs to compare equal for the same
+// process: clock ticks since boot from /proc on Linux, ps's lstart on other
+// Unix systems and the creation time in Windows file time units.
+func processStartTime(pid int) (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return "", err
+		}
+		// The command name in parentheses may contain spaces; the fields
+		// after it start with the state, field 3, and starttime is field 22.
+		end := bytes.LastIndexByte(data, ')')
+		if end < 0 {
+			return "", fmt.Errorf("unexpected /proc/%d/stat format", pid)
+		}
+		fields := strings.Fields(string(data[end+1:]))
+		if len(fields) < 20 {
+			return "", fmt.Errorf("unexpected /proc/%d/stat format", pid)
+		}
+		return fields[19], nil
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			fmt.Sprintf("(Get-Process -Id %d).StartTime.ToFileTimeUtc()", pid)).Output()
+		if err != nil {
+			return "", fmt.Errorf("powershell Get-Process: %w", err)
+		}
+		return nonEmptyOutput(out, pid)
+	default:
+		out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+		if err != nil {
+			return "", fmt.Errorf("ps: %w", err)
+		}
+		return nonEmptyOutput(out, pid)
+	}
+}
+
+func nonEmptyOutput(out []byte, pid int) (string, error) {
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return "", fmt.Errorf("no start time reported for pid %d", pid)
+	}
+	return text, nil
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
 	if runtime.GOOS == "windows" {
-		name += ".exe"
+		// FindProcess opens a handle on Windows, so success means it exists.
+		_ = proc.Release()
+		return true
 	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func githubBinaryName() string {
+	if runtime.GOOS == "windows" {
+		return "github-mcp-server.exe"
+	}
+	return "github-mcp-server"
+}
+
+func githubBinary() string {
+	name := githubBinaryName()
 	// Prefer PATH; otherwise fall back to ~/bin.
 	if p, err := exec.LookPath(name); err == nil {
 		return p
@@ -159,6 +745,61 @@ func githubBinary() string {
 	return ""
 }
 
+// toolHints explains how to install the executables run-mcps depends on.
+var toolHints = map[string]string{
+	"pnpm":                  "install Node.js, then `npm install -g pnpm` (https://pnpm.io/installation)",
+	"go":                    "install Go from https://go.dev/dl/",
+	"github-mcp-server":     "build it from https://github.com/github/github-mcp-server and put it on PATH or in ~/bin",
+	"github-mcp-server.exe": "build it from https://github.com/github/github-mcp-server and put it on PATH or in %USERPROFILE%\\bin",
+}
+
+// preflight checks that every executable the given specs need can be found,
+// reporting all missing tools at once instead of failing on the first start.
+func preflight(specs []procSpec) error {
+	users := map[string][]string{}
+	var order []string
+	for _, spec := range specs {
+		for _, tool := range requiredTools(spec) {
+			if _, seen := users[tool]; !seen {
+				order = append(order, tool)
+			}
+			users[tool] = append(users[tool], spec.name)
+		}
+	}
+
+	var missing []string
+	for _, tool := range order {
+		if _, err := exec.LookPath(tool); err == nil {
+			continue
+		}
+		line := fmt.Sprintf("  %s (needed by %s)", tool, strings.Join(users[tool], ", "))
+		if hint, ok := toolHints[filepath.Base(tool)]; ok {
+			line += ": " + hint
+		}
+		missing = append(missing, line)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required tools:\n%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// requiredTools returns the executables a spec runs: the launcher itself and,
+// for proxied servers, the command after "--".
+func requiredTools(spec procSpec) []string {
+	if len(spec.cmd) == 0 {
+		return nil
+	}
+	tools := []string{spec.cmd[0]}
+	for i, arg := range spec.cmd {
+		if arg == "--" && i+1 < len(spec.cmd) && spec.cmd[i+1] != spec.cmd[0] {
+			tools = append(tools, spec.cmd[i+1])
+			break
+		}
+	}
+	return tools
+}
+
 func portFor(name string, base int, storybookPort int, agentationPort int) int {
 	switch name {
 	case "tavily":
@@ -182,6 +823,274 @@ func portFor(name string, base int, storybookPort int, agentationPort int) int {
 	}
 }
 
+// parsePortOverrides parses "name=port,name=port" into a map, rejecting
+// unknown server names and invalid ports.
+func parsePortOverrides(raw string) (map[string]int, error) {
+	overrides := map[string]int{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isServerName(name) {
+			return nil, fmt.Errorf("invalid -ports entry %q: expected name=port with name one of %s", pair, strings.Join(serverNames, ", "))
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || !isValidPort(port) {
+			return nil, fmt.Errorf("invalid -ports entry %q: port must be between 1 and 65535", pair)
+		}
+		overrides[name] = port
+	}
+	return overrides, nil
+}
+
+// stopSignals maps the signal names accepted by -stop-signal to signals.
+var stopSignals = map[string]os.Signal{
+	"INT":  os.Interrupt,
+	"TERM": syscall.SIGTERM,
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": os.Kill,
+}
+
+// canonicalStopSignal accepts a signal name with or without the SIG prefix,
+// in any case, and returns its stopSignals key.
+func canonicalStopSignal(name string) (string, error) {
+	key := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	if key == "INTERRUPT" {
+		key = "INT"
+	}
+	if _, ok := stopSignals[key]; !ok {
+		return "", fmt.Errorf("unknown signal %q: use INT, TERM, HUP, QUIT or KILL", name)
+	}
+	return key, nil
+}
+
+// recordedStopSignal returns the signal named in a state file, falling back
+// to an interrupt for entries written before stop signals were recorded.
+func recordedStopSignal(name string) os.Signal {
+	if key, err := canonicalStopSignal(name); err == nil {
+		return stopSignals[key]
+	}
+	return os.Interrupt
+}
+
+// parseServerStopSignals parses "name:SIGNAL" entries into a map of
+// canonical signal names, rejecting unknown servers and signals.
+func parseServerStopSignals(entries []string) (map[string]string, error) {
+	byServer := map[string]string{}
+	for _, entry := range entries {
+		name, sig, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !isServerName(name) {
+			return nil, fmt.Errorf("invalid -server-stop-signal entry %q: expected name:SIGNAL with name one of %s", entry, strings.Join(serverNames, ", "))
+		}
+		key, err := canonicalStopSignal(sig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -server-stop-signal entry %q: %v", entry, err)
+		}
+		byServer[name] = key
+	}
+	return byServer, nil
+}
+
+// parseServerReadyPatterns parses "name:REGEX" entries into a map of
+// compiled patterns by server name.
+func parseServerReadyPatterns(entries []string) (map[string]*regexp.Regexp, error) {
+	byServer := map[string]*regexp.Regexp{}
+	for _, entry := range entries {
+		name, pattern, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !isServerName(name) || pattern == "" {
+			return nil, fmt.Errorf("invalid -server-ready-pattern entry %q: expected name:REGEX with name one of %s", entry, strings.Join(serverNames, ", "))
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -server-ready-pattern entry %q: %v", entry, err)
+		}
+		byServer[name] = re
+	}
+	return byServer, nil
+}
+
+// maxReadyLine caps how much of an unterminated output line is kept for
+// ready pattern matching.
+const maxReadyLine = 64 << 10
+
+// readyWatcher closes its channel the first time a line of a server's
+// output matches the server's ready pattern.
+type readyWatcher struct {
+	pattern *regexp.Regexp
+	ready   chan struct{}
+	once    sync.Once
+}
+
+// newReadyWatcher returns nil when there is no pattern to watch for.
+func newReadyWatcher(pattern *regexp.Regexp) *readyWatcher {
+	if pattern == nil {
+		return nil
+	}
+	return &readyWatcher{pattern: pattern, ready: make(chan struct{})}
+}
+
+// done is closed once the pattern matched; it is nil, and never ready, for
+// a nil watcher.
+func (r *readyWatcher) done() <-chan struct{} {
+	if r == nil {
+		return nil
+	}
+	return r.ready
+}
+
+// writer copies output to w and checks each complete line against the
+// pattern. Each stream needs its own writer.
+func (r *readyWatcher) writer(w io.Writer) io.Writer {
+	return &readyLineWriter{w: w, watcher: r}
+}
+
+type readyLineWriter struct {
+	w       io.Writer
+	watcher *readyWatcher
+	line    []byte
+}
+
+func (l *readyLineWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	select {
+	case <-l.watcher.ready:
+		return n, err
+	default:
+	}
+	l.line = append(l.line, p...)
+	for {
+		i := bytes.IndexByte(l.line, '\n')
+		if i < 0 {
+			break
+		}
+		if l.watcher.pattern.Match(l.line[:i]) {
+			l.watcher.once.Do(func() { close(l.watcher.ready) })
+			l.line = nil
+			return n, err
+		}
+		l.line = l.line[i+1:]
+	}
+	if len(l.line) > maxReadyLine {
+		l.line = l.line[len(l.line)-maxReadyLine:]
+	}
+	return n, err
+}
+
+// listFlag collects the values of a flag that may be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, " ") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseServerEnv groups "name:KEY=VALUE" entries (or "name:KEY" when
+// withValue is false) by server name, rejecting unknown servers and
+// malformed entries.
+func parseServerEnv(flagName string, entries []string, withValue bool) (map[string][]string, error) {
+	byServer := map[string][]string{}
+	format := "name:KEY"
+	if withValue {
+		format = "name:KEY=VALUE"
+	}
+	for _, entry := range entries {
+		name, kv, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !isServerName(name) {
+			return nil, fmt.Errorf("invalid %s entry %q: expected %s with name one of %s", flagName, entry, format, strings.Join(serverNames, ", "))
+		}
+		key, _, hasValue := strings.Cut(kv, "=")
+		if key == "" || strings.ContainsAny(key, " \t") || hasValue != withValue {
+			return nil, fmt.Errorf("invalid %s entry %q: expected %s", flagName, entry, format)
+		}
+		byServer[name] = append(byServer[name], kv)
+	}
+	return byServer, nil
+}
+
+// mergeEnv combines KEY=VALUE layers into one environment in which each key
+// appears once; a key set by a later layer overrides earlier ones but keeps
+// its original position. Keys compare case-insensitively on Windows. It
+// matches the test verifier's merge so both treat overrides the same way.
+func mergeEnv(layers ...[]string) []string {
+	var merged []string
+	index := make(map[string]int)
+	for _, layer := range layers {
+		for _, entry := range layer {
+			key := envKey(entry)
+			if i, ok := index[key]; ok {
+				merged[i] = entry
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+// unsetEnv returns env without the variables named in keys.
+func unsetEnv(env, keys []string) []string {
+	if len(keys) == 0 {
+		return env
+	}
+	drop := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		drop[envKey(key)] = true
+	}
+	kept := make([]string, 0, len(env))
+	for _, entry := range env {
+		if !drop[envKey(entry)] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// envKey returns the key of a KEY=VALUE entry in the form used for
+// comparisons, upper-cased on Windows where keys are case-insensitive.
+func envKey(entry string) string {
+	key, _, _ := strings.Cut(entry, "=")
+	if runtime.GOOS == "windows" {
+		key = strings.ToUpper(key)
+	}
+	return key
+}
+
+func isServerName(name string) bool {
+	for _, known := range serverNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPortConflicts reports every pair of servers assigned the same port.
+func checkPortConflicts(specs []procSpec) error {
+	owners := map[int]string{}
+	var conflicts []string
+	for _, spec := range specs {
+		if owner, taken := owners[spec.port]; taken {
+			conflicts = append(conflicts, fmt.Sprintf("%s and %s both use port %d", owner, spec.name, spec.port))
+			continue
+		}
+		owners[spec.port] = spec.name
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("port conflict: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
 func storybookCommand(projectDir, host string, port int) []string {
 	return []string{
 		"pnpm", "--dir", projectDir, "exec", "storybook", "dev",
@@ -189,8 +1098,16 @@ func storybookCommand(projectDir, host string, port int) []string {
 	}
 }
 
-func context7Command(host string, port int, key string) []string {
-	base := []string{"pnpm", "dlx", "mcp-proxy", "--host", host, "--port", fmt.Sprintf("%d", port), "--", "pnpm", "dlx", "@upstash/context7-mcp"}
+// proxyCommand wraps a stdio MCP server command in the proxy that exposes
+// it over HTTP on host:port.
+func proxyCommand(proxy []string, host string, port int, server ...string) []string {
+	cmd := append([]string{}, proxy...)
+	cmd = append(cmd, "--host", host, "--port", fmt.Sprintf("%d", port), "--")
+	return append(cmd, server...)
+}
+
+func context7Command(proxy []string, host string, port int, key string) []string {
+	base := proxyCommand(proxy, host, port, "pnpm", "dlx", "@upstash/context7-mcp")
 	if key == "" {
 		return base
 	}
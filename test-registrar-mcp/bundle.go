@@ -0,0 +1,117 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolImportBundle = "import_bundle"
+
+// bundleSchemaVersion is the newest bundle layout written by the verifier's
+// export_bundle that this registrar can read.
+const bundleSchemaVersion = 1
+
+type importBundleArgs struct {
+	Bundle     bundle `json:"bundle" jsonschema:"Bundle returned by the test-verifier's export_bundle"`
+	Overwrite  bool   `json:"overwrite,omitempty" jsonschema:"Replace the config if one is already registered; without it the import is refused"`
+	ConfigPath string `json:"config_path,omitempty" jsonschema:"Optional config file to write instead of the server default (TEST_VERIFIER_CONFIG)"`
+}
+
+// bundle mirrors the verifier's export_bundle output. Only the config is
+// imported; the other fields are accepted so a bundle can be passed as is.
+type bundle struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    string         `json:"exported_at,omitempty"`
+	ConfigPath    string         `json:"config_path,omitempty"`
+	Fingerprint   string         `json:"fingerprint,omitempty"`
+	Config        map[string]any `json:"config"`
+	RecentRuns    []any          `json:"recent_runs,omitempty"`
+	RedactedEnv   []string       `json:"redacted_env,omitempty"`
+}
+
+type importBundleResult struct {
+	ConfigPath  string   `json:"config_path"`
+	Command     []string `json:"command"`
+	Fingerprint string   `json:"fingerprint"`
+	Replaced    bool     `json:"replaced"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+func registerImportBundleTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolImportBundle,
+		Description: "Register the config from a test-verifier export_bundle, e.g. to reproduce a verified setup on another machine. The config is validated like register_test_command. An existing config is only replaced with overwrite.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args importBundleArgs) (*mcp.CallToolResult, importBundleResult, error) {
+		b := args.Bundle
+		if b.SchemaVersion < 1 || b.SchemaVersion > bundleSchemaVersion {
+			return nil, importBundleResult{}, fmt.Errorf("unsupported bundle schema_version %d (this registrar reads up to %d)", b.SchemaVersion, bundleSchemaVersion)
+		}
+		if len(b.RedactedEnv) > 0 {
+			return nil, importBundleResult{}, fmt.Errorf("bundle has redacted env values (%s); export it again with include_secrets, or register the config with register_test_command and your own env values", strings.Join(b.RedactedEnv, ", "))
+		}
+		if len(b.Config) == 0 {
+			return nil, importBundleResult{}, errors.New("bundle has no config")
+		}
+
+		data, err := json.Marshal(b.Config)
+		if err != nil {
+			return nil, importBundleResult{}, fmt.Errorf("failed to read bundle config: %w", err)
+		}
+		var reg registerArgs
+		if err := json.Unmarshal(data, &reg); err != nil {
+			return nil, importBundleResult{}, fmt.Errorf("invalid bundle config: %w", err)
+		}
+		// The exported config was accepted once already; shell tokens in it
+		// are reported as warnings rather than refused again.
+		reg.ConfigPath, reg.AllowShellTokens = "", true
+		cfg, warnings, err := newStoredConfig(reg)
+		if err != nil {
+			return nil, importBundleResult{}, fmt.Errorf("invalid bundle config: %w", err)
+		}
+
+		cfgPath, err := configPath(args.ConfigPath)
+		if err != nil {
+			return nil, importBundleResult{}, err
+		}
+		_, statErr := os.Stat(cfgPath)
+		replaced := statErr == nil
+		if replaced && !args.Overwrite {
+			return nil, importBundleResult{}, fmt.Errorf("a config is already registered at %s; pass overwrite to replace it", cfgPath)
+		}
+		if err := checkRegistrationRate(); err != nil {
+			return nil, importBundleResult{}, err
+		}
+		if err := writeConfig(cfgPath, cfg); err != nil {
+			return nil, importBundleResult{}, err
+		}
+		fingerprint, err := configFingerprint(cfg)
+		if err != nil {
+			return nil, importBundleResult{}, err
+		}
+		if b.Fingerprint != "" && b.Fingerprint != fingerprint {
+			warnings = append(warnings, "the imported config's fingerprint differs from the exported one, e.g. because paths were normalized on this machine")
+		}
+
+		result := importBundleResult{
+			ConfigPath:  cfgPath,
+			Command:     cfg.Command,
+			Fingerprint: fingerprint,
+			Replaced:    replaced,
+			Warnings:    warnings,
+		}
+		message := fmt.Sprintf("Imported the bundle config into %s.", cfgPath)
+		for _, warning := range warnings {
+			message += " Warning: " + warning + "."
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
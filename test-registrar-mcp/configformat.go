@@ -0,0 +1,128 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFormatJSON = "json"
+	configFormatYAML = "yaml"
+	configFormatTOML = "toml"
+)
+
+// configFormat picks the config file format from the extension of path:
+// .yaml or .yml for YAML, .toml for TOML, and JSON for anything else.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return configFormatYAML
+	case ".toml":
+		return configFormatTOML
+	}
+	return configFormatJSON
+}
+
+// marshalConfig encodes cfg in the format of path. YAML and TOML use the
+// same field names as JSON.
+func marshalConfig(path string, cfg storedConfig) ([]byte, error) {
+	format := configFormat(path)
+	if format == configFormatJSON {
+		return json.MarshalIndent(cfg, "", "  ")
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	fields = normalizeConfigValue(fields).(map[string]any)
+
+	var buf bytes.Buffer
+	if format == configFormatYAML {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(fields); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		if err := enc.Encode(fields); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalConfig decodes data, read from path, into cfg according to the
+// format of path.
+func unmarshalConfig(path string, data []byte, cfg *storedConfig) error {
+	var fields map[string]any
+	switch configFormat(path) {
+	case configFormatYAML:
+		if err := yaml.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+	case configFormatTOML:
+		if err := toml.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("invalid TOML: %w", err)
+		}
+	default:
+		return json.Unmarshal(data, cfg)
+	}
+	data, err := json.Marshal(normalizeConfigValue(fields))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cfg)
+}
+
+// normalizeConfigValue turns decoded YAML, TOML or JSON values into ones
+// every encoder accepts: maps get string keys, and JSON numbers become
+// int64 when whole, so TOML does not write 600 as 600.0.
+func normalizeConfigValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = normalizeConfigValue(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeConfigValue(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = normalizeConfigValue(value)
+		}
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return v
+}
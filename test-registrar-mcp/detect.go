@@ -0,0 +1,186 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolDetect = "detect_test_command"
+
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+)
+
+// npmDefaultTestScript is the placeholder "npm init" writes, which is not a
+// real test command.
+const npmDefaultTestScript = `echo "Error: no test specified" && exit 1`
+
+// makeTestTarget matches a "test:" rule in a Makefile.
+var makeTestTarget = regexp.MustCompile(`^test\s*:`)
+
+type detectArgs struct {
+	WorkingDir string `json:"working_dir,omitempty" jsonschema:"Project directory to inspect (default: the server's working directory)"`
+}
+
+type suggestion struct {
+	Command    []string `json:"command"`
+	Confidence string   `json:"confidence" jsonschema:"high or medium"`
+	Evidence   string   `json:"evidence"`
+}
+
+type detectResult struct {
+	WorkingDir  string       `json:"working_dir"`
+	Suggestions []suggestion `json:"suggestions"`
+}
+
+func registerDetectTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolDetect,
+		Description: "Suggest test commands for a project by inspecting its working directory (package.json test script, go.mod, Makefile test target, pyproject.toml, Cargo.toml). Nothing is registered; pass a suggestion to register_test_command.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args detectArgs) (*mcp.CallToolResult, detectResult, error) {
+		dir, err := expandHome(strings.TrimSpace(args.WorkingDir))
+		if err != nil {
+			return nil, detectResult{}, err
+		}
+		if dir == "" {
+			dir = "."
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, detectResult{}, err
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, detectResult{}, fmt.Errorf("working_dir does not exist: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, detectResult{}, fmt.Errorf("working_dir is not a directory: %s", dir)
+		}
+
+		suggestions := detectTestCommands(dir)
+		result := detectResult{WorkingDir: dir, Suggestions: suggestions}
+		message := fmt.Sprintf("No test command detected in %s.", dir)
+		if len(suggestions) > 0 {
+			lines := make([]string, 0, len(suggestions))
+			for _, s := range suggestions {
+				lines = append(lines, fmt.Sprintf("- %s (%s confidence): %s", strings.Join(s.Command, " "), s.Confidence, s.Evidence))
+			}
+			message = fmt.Sprintf("Suggested test commands for %s:\n%s", dir, strings.Join(lines, "\n"))
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
+
+// detectTestCommands returns suggestions for dir, high confidence first.
+func detectTestCommands(dir string) []suggestion {
+	high, medium := []suggestion{}, []suggestion{}
+	add := func(s suggestion) {
+		if s.Confidence == confidenceHigh {
+			high = append(high, s)
+		} else {
+			medium = append(medium, s)
+		}
+	}
+
+	if s, ok := detectPackageJSON(dir); ok {
+		add(s)
+	}
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		add(suggestion{Command: []string{"go", "test", "./..."}, Confidence: confidenceHigh, Evidence: "go.mod found"})
+	}
+	if s, ok := detectMakefile(dir); ok {
+		add(s)
+	}
+	if s, ok := detectPython(dir); ok {
+		add(s)
+	}
+	if fileExists(filepath.Join(dir, "Cargo.toml")) {
+		add(suggestion{Command: []string{"cargo", "test"}, Confidence: confidenceHigh, Evidence: "Cargo.toml found"})
+	}
+	return append(high, medium...)
+}
+
+func detectPackageJSON(dir string) (suggestion, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return suggestion{}, false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return suggestion{}, false
+	}
+	script := strings.TrimSpace(pkg.Scripts["test"])
+	if script == "" || script == npmDefaultTestScript {
+		return suggestion{}, false
+	}
+
+	command, lockfile := []string{"npm", "test"}, ""
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		command, lockfile = []string{"pnpm", "test"}, "pnpm-lock.yaml"
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		command, lockfile = []string{"yarn", "test"}, "yarn.lock"
+	case fileExists(filepath.Join(dir, "bun.lockb")), fileExists(filepath.Join(dir, "bun.lock")):
+		command, lockfile = []string{"bun", "run", "test"}, "a bun lockfile"
+	}
+	evidence := fmt.Sprintf("package.json test script %q", script)
+	if lockfile != "" {
+		evidence += ", " + lockfile + " found"
+	}
+	return suggestion{Command: command, Confidence: confidenceHigh, Evidence: evidence}, true
+}
+
+func detectMakefile(dir string) (suggestion, bool) {
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if makeTestTarget.MatchString(scanner.Text()) {
+				return suggestion{Command: []string{"make", "test"}, Confidence: confidenceHigh, Evidence: name + " has a test target"}, true
+			}
+		}
+		// make reads only the first of these names that exists.
+		return suggestion{}, false
+	}
+	return suggestion{}, false
+}
+
+func detectPython(dir string) (suggestion, bool) {
+	pytest := []string{"python", "-m", "pytest"}
+	if fileExists(filepath.Join(dir, "pytest.ini")) {
+		return suggestion{Command: pytest, Confidence: confidenceHigh, Evidence: "pytest.ini found"}, true
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
+	if err != nil {
+		return suggestion{}, false
+	}
+	if strings.Contains(string(data), "[tool.pytest") {
+		return suggestion{Command: pytest, Confidence: confidenceHigh, Evidence: "pyproject.toml configures pytest"}, true
+	}
+	if strings.Contains(string(data), "pytest") {
+		return suggestion{Command: pytest, Confidence: confidenceMedium, Evidence: "pyproject.toml mentions pytest"}, true
+	}
+	return suggestion{Command: pytest, Confidence: confidenceMedium, Evidence: "pyproject.toml found; pytest assumed"}, true
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,155 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// redactedValue replaces env values in a dry_run preview.
+const redactedValue = "[redacted]"
+
+// configChange is one field a registration would change. Old is absent for
+// a field being set and New for a field being cleared; env entries are
+// listed per key as env.KEY with their values redacted.
+type configChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+// previewChanges compares cfg with the config currently at path, which may
+// not exist yet.
+func previewChanges(path string, cfg storedConfig) ([]configChange, error) {
+	var current storedConfig
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read current config: %w", err)
+	}
+	if err == nil {
+		if err := unmarshalConfig(path, data, &current); err != nil {
+			return nil, fmt.Errorf("current config at %s cannot be parsed: %w", path, err)
+		}
+	}
+	return diffConfigs(current, cfg)
+}
+
+// diffConfigs compares two configs field by field through their JSON form,
+// ignoring when they were saved.
+func diffConfigs(old, updated storedConfig) ([]configChange, error) {
+	oldFields, err := configFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := configFields(updated)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []configChange
+	for _, name := range names {
+		before, hadOld := oldFields[name]
+		after, hasNew := newFields[name]
+		if hadOld && hasNew && bytes.Equal(before, after) {
+			continue
+		}
+		change := configChange{Field: name}
+		if hadOld {
+			_ = json.Unmarshal(before, &change.Old)
+		}
+		if hasNew {
+			_ = json.Unmarshal(after, &change.New)
+		}
+		changes = append(changes, change)
+	}
+	return append(changes, diffEnv(old.Env, updated.Env)...), nil
+}
+
+func configFields(cfg storedConfig) (map[string]json.RawMessage, error) {
+	cfg.UpdatedAt = ""
+	cfg.Env = nil
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize config: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "updated_at")
+	return fields, nil
+}
+
+// diffEnv reports added, removed and changed env keys without their
+// values.
+func diffEnv(old, updated []string) []configChange {
+	oldValues, newValues := envValues(old), envValues(updated)
+	keys := make([]string, 0, len(oldValues)+len(newValues))
+	for key := range oldValues {
+		keys = append(keys, key)
+	}
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []configChange
+	for _, key := range keys {
+		before, hadOld := oldValues[key]
+		after, hasNew := newValues[key]
+		if hadOld && hasNew && before == after {
+			continue
+		}
+		change := configChange{Field: "env." + key}
+		if hadOld {
+			change.Old = redactedValue
+		}
+		if hasNew {
+			change.New = redactedValue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func envValues(env []string) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		values[key] = value
+	}
+	return values
+}
+
+// redactEnvValues returns env with every value replaced by redactedValue.
+func redactEnvValues(env []string) []string {
+	if env == nil {
+		return nil
+	}
+	out := make([]string, 0, len(env))
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		out = append(out, key+"="+redactedValue)
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	configModeEnvVar    = "TEST_VERIFIER_CONFIG_MODE"
+	configDirModeEnvVar = "TEST_VERIFIER_CONFIG_DIR_MODE"
+
+	defaultConfigMode    os.FileMode = 0600
+	defaultConfigDirMode os.FileMode = 0755
+)
+
+// configModes returns the permissions for the config file and for
+// directories created to hold it, from TEST_VERIFIER_CONFIG_MODE and
+// TEST_VERIFIER_CONFIG_DIR_MODE (octal, e.g. 0640) or the defaults.
+func configModes() (file, dir os.FileMode, err error) {
+	file, err = modeFromEnv(configModeEnvVar, defaultConfigMode, 0600)
+	if err != nil {
+		return 0, 0, err
+	}
+	dir, err = modeFromEnv(configDirModeEnvVar, defaultConfigDirMode, 0700)
+	if err != nil {
+		return 0, 0, err
+	}
+	return file, dir, nil
+}
+
+// modeFromEnv parses an octal permission mode. The mode must keep the owner
+// bits in required, so the registrar can still rewrite what it created.
+func modeFromEnv(name string, fallback, required os.FileMode) (os.FileMode, error) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(v, "0o"), 8, 32)
+	if err != nil || n > 0777 {
+		return 0, fmt.Errorf("invalid %s=%q: want an octal permission mode such as 0640", name, v)
+	}
+	mode := os.FileMode(n)
+	if mode&required != required {
+		return 0, fmt.Errorf("invalid %s=%q: mode must include owner permissions %04o", name, v, required)
+	}
+	return mode, nil
+}
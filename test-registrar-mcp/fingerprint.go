@@ -0,0 +1,72 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolFingerprint = "config_fingerprint"
+
+type fingerprintArgs struct {
+	ConfigPath string `json:"config_path,omitempty" jsonschema:"Optional config file to fingerprint instead of the server default (TEST_VERIFIER_CONFIG)"`
+}
+
+type fingerprintResult struct {
+	ConfigPath  string `json:"config_path"`
+	Fingerprint string `json:"fingerprint"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+}
+
+func registerFingerprintTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolFingerprint,
+		Description: "Return a SHA-256 fingerprint of the registered test config that ignores when it was saved and the order of env entries, so callers can tell a real change from a re-save.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args fingerprintArgs) (*mcp.CallToolResult, fingerprintResult, error) {
+		cfgPath, err := configPath(args.ConfigPath)
+		if err != nil {
+			return nil, fingerprintResult{}, err
+		}
+		data, err := os.ReadFile(cfgPath)
+		if err != nil {
+			return nil, fingerprintResult{}, fmt.Errorf("failed to read config: %w", err)
+		}
+		var cfg storedConfig
+		if err := unmarshalConfig(cfgPath, data, &cfg); err != nil {
+			return nil, fingerprintResult{}, fmt.Errorf("failed to parse config: %w", err)
+		}
+		fingerprint, err := configFingerprint(cfg)
+		if err != nil {
+			return nil, fingerprintResult{}, err
+		}
+		result := fingerprintResult{
+			ConfigPath:  cfgPath,
+			Fingerprint: fingerprint,
+			UpdatedAt:   cfg.UpdatedAt,
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fingerprint}}}, result, nil
+	})
+}
+
+// configFingerprint hashes the canonical JSON form of cfg: UpdatedAt is
+// dropped and env entries are sorted, so only meaningful changes alter it.
+func configFingerprint(cfg storedConfig) (string, error) {
+	cfg.UpdatedAt = ""
+	cfg.Env = append([]string(nil), cfg.Env...)
+	sort.Strings(cfg.Env)
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -4,7 +4,11 @@ go 1.23.0
 
 toolchain go1.24.0
 
-require github.com/modelcontextprotocol/go-sdk v1.2.0
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/modelcontextprotocol/go-sdk v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/jsonschema-go v0.3.0 // indirect
@@ -0,0 +1,89 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolHealth = "health"
+
+	serverName    = "test-registrar"
+	serverVersion = "0.1.0"
+)
+
+// startedAt is when the server process started, for health uptime.
+var startedAt = time.Now()
+
+type healthArgs struct{}
+
+type healthResult struct {
+	Server        string `json:"server"`
+	Version       string `json:"version"`
+	StartedAt     string `json:"started_at"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	ConfigPath    string `json:"config_path,omitempty"`
+	// ConfigReady is set when the config path holds a config that parses;
+	// ConfigError says why it does not otherwise.
+	ConfigReady bool   `json:"config_ready"`
+	ConfigError string `json:"config_error,omitempty"`
+}
+
+func registerHealthTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolHealth,
+		Description: "Cheap liveness probe: report the server name, version and uptime, the config path it writes, and whether a config is already registered there. Changes nothing.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args healthArgs) (*mcp.CallToolResult, healthResult, error) {
+		result := healthResult{
+			Server:        serverName,
+			Version:       serverVersion,
+			StartedAt:     startedAt.UTC().Format(time.RFC3339),
+			UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		}
+		if err := checkRegisteredConfig(&result); err != nil {
+			result.ConfigError = err.Error()
+		} else {
+			result.ConfigReady = true
+		}
+
+		summary := fmt.Sprintf("%s %s up for %ds; config %s is registered.", serverName, serverVersion, result.UptimeSeconds, result.ConfigPath)
+		if !result.ConfigReady {
+			summary = fmt.Sprintf("%s %s up for %ds; config not ready: %s", serverName, serverVersion, result.UptimeSeconds, result.ConfigError)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, result, nil
+	})
+}
+
+// checkRegisteredConfig resolves the default config path into result and
+// checks that a parsable config is registered there.
+func checkRegisteredConfig(result *healthResult) error {
+	path, err := configPath("")
+	if err != nil {
+		return err
+	}
+	result.ConfigPath = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no test command registered yet at %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var cfg storedConfig
+	if err := unmarshalConfig(path, data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(cfg.Command) == 0 {
+		return fmt.Errorf("config at %s has no command", path)
+	}
+	return nil
+}
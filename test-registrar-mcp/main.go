@@ -5,11 +5,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,19 +23,200 @@ import (
 const (
 	toolRegister = "register_test_command"
 	configEnvVar = "TEST_VERIFIER_CONFIG"
+	minNice      = -20
+	maxNice      = 19
+
+	// envFilePrefix marks an env value the verifier reads from a file at run
+	// time, e.g. GITHUB_TOKEN=@/run/secrets/gh; @@ escapes a literal @.
+	envFilePrefix = "@"
 )
 
 type storedConfig struct {
 	Command    []string `json:"command"`
 	WorkingDir string   `json:"working_dir,omitempty"`
 	Env        []string `json:"env,omitempty"`
+	Nice       int      `json:"nice,omitempty"`
 	UpdatedAt  string   `json:"updated_at,omitempty"`
+
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty"`
+	Container        *containerConfig  `json:"container,omitempty"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty"`
+
+	// OutputEncoding is the encoding the command writes (default UTF-8).
+	// OutputFormat is the structured format of its stdout, e.g. "tap".
+	OutputEncoding    string `json:"output_encoding,omitempty"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty"`
+	OutputFormat      string `json:"output_format,omitempty"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty"`
+	// CacheSources are globs, relative to WorkingDir, of the files whose
+	// content decides whether the verifier may reuse a successful result.
+	CacheSources []string `json:"cache_sources,omitempty"`
+
+	// InheritEnv is how much of the verifier's environment the command
+	// gets: all (default), none, or allowlist, which keeps InheritEnvKeys.
+	InheritEnv     string   `json:"inherit_env,omitempty"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty"`
+
+	// FailOnOutputPatterns are regexps that fail a run when they match its
+	// stdout or stderr, even if the command exited zero.
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty"`
+	// NoTestsPatterns detect runs that executed no tests (default: pytest,
+	// Jest and Vitest messages); FailOnNoTests fails such runs.
+	NoTestsPatterns []string `json:"no_tests_patterns,omitempty"`
+	FailOnNoTests   bool     `json:"fail_on_no_tests,omitempty"`
+
+	// ArgsFromFile names a manifest, relative to WorkingDir unless absolute,
+	// whose lines the verifier appends to Command when a run starts.
+	ArgsFromFile string `json:"args_from_file,omitempty"`
+
+	// ExpandGlobs asks the verifier to expand glob arguments relative to
+	// WorkingDir; FailUnmatchedGlobs makes a pattern without matches an error.
+	ExpandGlobs        bool `json:"expand_globs,omitempty"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty"`
+
+	// TemplateArgs asks the verifier to replace ${VAR} in command arguments
+	// with the run's environment; unset variables are an error unless
+	// AllowUnsetTemplateVars expands them to "".
+	TemplateArgs           bool `json:"template_args,omitempty"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty"`
+
+	// GitHubReport is where the verifier sets a commit status for runs made
+	// with report_to_github.
+	GitHubReport *githubReportConfig `json:"github_report,omitempty"`
+
+	// SmokeCommand is a quick check the verifier runs before Command when
+	// run_tests is called with smoke_first.
+	SmokeCommand []string `json:"smoke_command,omitempty"`
+
+	// SummaryCommand turns a finished run's output, on its stdin, into the
+	// short summary the verifier returns.
+	SummaryCommand []string `json:"summary_command,omitempty"`
+
+	// VerboseArgs and RaceArgs are what the verifier's run_tests appends
+	// to the command when called with verbose or race.
+	VerboseArgs []string `json:"verbose_args,omitempty"`
+	RaceArgs    []string `json:"race_args,omitempty"`
+
+	// FailFastFlag is the runner's stop-at-first-failure flag, appended by
+	// run_tests with fail_fast.
+	FailFastFlag string `json:"fail_fast_flag,omitempty"`
+
+	// RunAsUser and RunAsGroup are the user and group, by name or numeric
+	// ID, the verifier runs the command as on Unix.
+	RunAsUser  string `json:"run_as_user,omitempty"`
+	RunAsGroup string `json:"run_as_group,omitempty"`
+
+	// AnomalyFactor makes run_tests flag runs whose duration or output size
+	// is this many times above or below the config's recent median.
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty"`
+
+	// KillLadder lists the signals the verifier sends in turn to a timed-out
+	// or cancelled run's process group before SIGKILL.
+	KillLadder []killStep `json:"kill_ladder,omitempty"`
+
+	// EnsureDirs are directories, relative to WorkingDir, that the verifier
+	// creates before each run if they do not exist.
+	EnsureDirs []string `json:"ensure_dirs,omitempty"`
+
+	// TrackFileChanges makes the verifier report the files each run
+	// created, modified or deleted under WorkingDir.
+	TrackFileChanges bool `json:"track_file_changes,omitempty"`
+
+	// Hermetic makes the verifier add environment variables that ask common
+	// toolchains to work offline, HermeticEnv replacing its defaults. It
+	// does not block network access.
+	Hermetic    bool     `json:"hermetic,omitempty"`
+	HermeticEnv []string `json:"hermetic_env,omitempty"`
+}
+
+// containerConfig asks the verifier to run the command inside a Docker
+// container, with the working directory mounted at Workdir.
+type containerConfig struct {
+	Image   string   `json:"image" jsonschema:"Container image to run the command in, e.g. golang:1.23"`
+	Mounts  []string `json:"mounts,omitempty" jsonschema:"Extra bind mounts as HOST:CONTAINER[:OPTIONS]"`
+	Workdir string   `json:"workdir,omitempty" jsonschema:"Path inside the container where the working directory is mounted (default /workspace)"`
+}
+
+// githubReportConfig names the repository the verifier sets commit statuses
+// on and how it finds the token.
+type githubReportConfig struct {
+	Repo     string `json:"repo" jsonschema:"Repository as owner/name"`
+	Context  string `json:"context,omitempty" jsonschema:"Name of the status check (default test-verifier)"`
+	TokenEnv string `json:"token_env,omitempty" jsonschema:"Environment variable holding the GitHub token, looked up in the registered env and then the verifier's environment (default GITHUB_TOKEN, then GITHUB_PERSONAL_ACCESS_TOKEN)"`
+}
+
+// killStep is one rung of a kill_ladder: the signal sent to the command's
+// process group and how long to wait for it to exit before the next rung.
+type killStep struct {
+	Signal      string `json:"signal" jsonschema:"INT, TERM, HUP or QUIT"`
+	WaitSeconds int    `json:"wait_seconds" jsonschema:"Seconds to wait for the command to exit before the next rung"`
+}
+
+// filterRule asks the verifier to rewrite matches of Pattern in captured
+// output with Replacement.
+type filterRule struct {
+	Pattern     string `json:"pattern" jsonschema:"Go regular expression to match in stdout and stderr"`
+	Replacement string `json:"replacement,omitempty" jsonschema:"Replacement text; $1 and ${name} expand to capture groups"`
 }
 
 type registerArgs struct {
 	Command    []string `json:"command" jsonschema:"Command and arguments to run the tests, e.g. [\"npm\",\"test\"]"`
 	WorkingDir string   `json:"working_dir,omitempty" jsonschema:"Optional working directory for running the command"`
 	Env        []string `json:"env,omitempty" jsonschema:"Optional environment variables as KEY=VALUE"`
+	Nice       int      `json:"nice,omitempty" jsonschema:"Optional CPU priority for test runs as a nice level (-20 to 19); higher values are less disruptive"`
+
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty" jsonschema:"Optional explanations for known exit codes, keyed by code, e.g. {\"5\":\"no tests were collected\"}"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty" jsonschema:"Exit codes that count as success (default [0]), e.g. [0,1] for a linter that exits 1 on warnings; timeouts and signals still fail"`
+	Container        *containerConfig  `json:"container,omitempty" jsonschema:"Optional Docker container to run the command in instead of the host"`
+	AllowShellTokens bool              `json:"allow_shell_tokens,omitempty" jsonschema:"Register even if the command contains shell operators such as && or |, which are passed to the program literally"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty" jsonschema:"Optional substrings that mark the start of a failure report, used by run_tests extract_failures (default FAIL, Error:, panic:, AssertionError, ✕)"`
+	ConfigPath       string            `json:"config_path,omitempty" jsonschema:"Optional config file to write instead of the server default (TEST_VERIFIER_CONFIG)"`
+	DryRun           bool              `json:"dry_run,omitempty" jsonschema:"Validate and return the changes against the current config, field by field with env values redacted, without writing anything"`
+
+	OutputEncoding    string `json:"output_encoding,omitempty" jsonschema:"Encoding the command writes its output in: utf-8 (default), windows-1252 or iso-8859-1; output is always returned as valid UTF-8"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty" jsonschema:"Convert CRLF line endings in captured output to LF"`
+	OutputFormat      string `json:"output_format,omitempty" jsonschema:"Structured format the command writes its test results in on stdout: tap (Test Anything Protocol). run_tests then reports each test in tests and fails the run on failed or missing tests"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty" jsonschema:"Optional regexp replacements applied in order to captured stdout and stderr, e.g. to normalize paths or timestamps"`
+	CacheSources  []string     `json:"cache_sources,omitempty" jsonschema:"Optional globs relative to working_dir (** matches any directories), e.g. [\"**/*.go\",\"go.sum\"]; when set, run_tests reuses the last successful result while these files and the config are unchanged"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty" jsonschema:"How much of the verifier's environment the command gets: all (default), none (only env above) or allowlist (only inherit_env_keys)"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty" jsonschema:"Verifier environment variables to pass through in allowlist mode, e.g. [\"PATH\",\"HOME\"]"`
+
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty" jsonschema:"Optional Go regexps that fail a run when they match stdout or stderr, even with exit code 0, e.g. [\"WARNING: DATA RACE\"]"`
+	NoTestsPatterns      []string `json:"no_tests_patterns,omitempty" jsonschema:"Optional Go regexps whose match in the output means no tests ran (default: pytest, Jest and Vitest messages); reported as no_tests_ran"`
+	FailOnNoTests        bool     `json:"fail_on_no_tests,omitempty" jsonschema:"Fail a run whose output says no tests ran, even with exit code 0"`
+	ArgsFromFile         string   `json:"args_from_file,omitempty" jsonschema:"Optional manifest file, relative to working_dir unless absolute, whose lines are appended to the command at run time; blank lines and # comments are skipped"`
+
+	ExpandGlobs        bool `json:"expand_globs,omitempty" jsonschema:"Expand glob arguments such as src/**/*.js relative to working_dir at run time, as a shell would; flags are left alone"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty" jsonschema:"With expand_globs, fail the run when a pattern matches nothing instead of passing it through literally"`
+
+	TemplateArgs           bool `json:"template_args,omitempty" jsonschema:"Replace ${VAR} in command arguments with the run's environment, e.g. [\"go\",\"test\",\"-run\",\"${TEST_PATTERN}\"] with env TEST_PATTERN=... on run_tests; $${ escapes a literal ${"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty" jsonschema:"With template_args, expand unset variables to an empty string instead of failing the run"`
+
+	GitHubReport *githubReportConfig `json:"github_report,omitempty" jsonschema:"Optional GitHub repository on which run_tests with report_to_github sets a commit status"`
+	SmokeCommand []string            `json:"smoke_command,omitempty" jsonschema:"Optional quick command, e.g. [\"go\",\"vet\",\"./...\"], that run_tests with smoke_first runs before the full command"`
+	KillLadder   []killStep          `json:"kill_ladder,omitempty" jsonschema:"Optional signals sent in turn to the process group of a timed-out or cancelled run, e.g. [{\"signal\":\"TERM\",\"wait_seconds\":10}], before SIGKILL (Unix only; default: kill at once)"`
+	EnsureDirs   []string            `json:"ensure_dirs,omitempty" jsonschema:"Optional directories, relative to working_dir, that run_tests creates before running if missing, e.g. [\"test-results\",\"coverage\"]"`
+
+	TrackFileChanges bool `json:"track_file_changes,omitempty" jsonschema:"Hash the files under working_dir (skipping .git, up to 10000 files) before and after each run and report the created, modified and deleted paths in file_changes"`
+
+	Hermetic    bool     `json:"hermetic,omitempty" jsonschema:"Best effort offline runs: set environment variables that ask common toolchains (Go, npm, yarn, pip, uv, cargo) not to download anything, and warn when the command looks network-dependent. Network access is not blocked"`
+	HermeticEnv []string `json:"hermetic_env,omitempty" jsonschema:"Optional KEY=VALUE entries that replace the default hermetic variables, e.g. [\"GOPROXY=off\"]; env still takes precedence"`
+
+	SummaryCommand []string `json:"summary_command,omitempty" jsonschema:"Optional command, e.g. [\"python\",\"scripts/summarize.py\"], that receives a finished run's stdout then stderr on stdin and prints a short summary, returned as summary; it runs in working_dir with a 10 second timeout, and run_tests falls back to its own summary if it fails"`
+
+	VerboseArgs []string `json:"verbose_args,omitempty" jsonschema:"Optional arguments that run_tests with verbose appends to the command, e.g. [\"-v\"]"`
+	RaceArgs    []string `json:"race_args,omitempty" jsonschema:"Optional arguments that run_tests with race appends to the command to turn on a race detector or sanitizer, e.g. [\"-race\"] for go test"`
+
+	FailFastFlag string `json:"fail_fast_flag,omitempty" jsonschema:"Optional flag that makes the runner stop at the first failing test, e.g. -failfast for go test or --bail for jest; run_tests with fail_fast appends it"`
+
+	RunAsUser  string `json:"run_as_user,omitempty" jsonschema:"Optional user, by name or numeric ID, to run the command as instead of the verifier's own user (Unix only; the verifier must run as root to switch users)"`
+	RunAsGroup string `json:"run_as_group,omitempty" jsonschema:"Optional group, by name or numeric ID, to run the command as (default: run_as_user's groups)"`
+
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty" jsonschema:"Optional factor, greater than 1, that turns on anomaly detection: run_tests flags a run whose duration or output size is this many times above or below the median of the config's recent successful runs (e.g. 3)"`
 }
 
 type registerResult struct {
@@ -39,21 +224,94 @@ type registerResult struct {
 	Command    []string `json:"command"`
 	WorkingDir string   `json:"working_dir,omitempty"`
 	Env        []string `json:"env,omitempty"`
+	Nice       int      `json:"nice,omitempty"`
 	UpdatedAt  string   `json:"updated_at"`
 	Message    string   `json:"message"`
+
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty"`
+	Container        *containerConfig  `json:"container,omitempty"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty"`
+
+	OutputEncoding    string `json:"output_encoding,omitempty"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty"`
+	OutputFormat      string `json:"output_format,omitempty"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty"`
+	CacheSources  []string     `json:"cache_sources,omitempty"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty"`
+
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty"`
+	NoTestsPatterns      []string `json:"no_tests_patterns,omitempty"`
+	FailOnNoTests        bool     `json:"fail_on_no_tests,omitempty"`
+	ArgsFromFile         string   `json:"args_from_file,omitempty"`
+
+	ExpandGlobs        bool `json:"expand_globs,omitempty"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty"`
+
+	TemplateArgs           bool `json:"template_args,omitempty"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty"`
+
+	GitHubReport *githubReportConfig `json:"github_report,omitempty"`
+	SmokeCommand []string            `json:"smoke_command,omitempty"`
+	KillLadder   []killStep          `json:"kill_ladder,omitempty"`
+	EnsureDirs   []string            `json:"ensure_dirs,omitempty"`
+
+	TrackFileChanges bool `json:"track_file_changes,omitempty"`
+
+	Hermetic    bool     `json:"hermetic,omitempty"`
+	HermeticEnv []string `json:"hermetic_env,omitempty"`
+
+	SummaryCommand []string `json:"summary_command,omitempty"`
+
+	VerboseArgs []string `json:"verbose_args,omitempty"`
+	RaceArgs    []string `json:"race_args,omitempty"`
+
+	FailFastFlag string `json:"fail_fast_flag,omitempty"`
+
+	RunAsUser  string `json:"run_as_user,omitempty"`
+	RunAsGroup string `json:"run_as_group,omitempty"`
+
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty"`
+
+	Fingerprint string `json:"fingerprint"`
+
+	// DryRun is set when nothing was written; Changes then lists what
+	// registering would change.
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Changes []configChange `json:"changes,omitempty"`
 }
 
-func main() {
+// newServer builds the registrar with all of its tools, ready to run on any
+// transport.
+func newServer() *mcp.Server {
 	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "test-registrar",
+		Name:    serverName,
 		Title:   "Test Command Registrar MCP Server",
-		Version: "0.1.0",
+		Version: serverVersion,
 	}, &mcp.ServerOptions{
 		Instructions: "Register the test command with register_test_command. This server writes the shared config file used by the test-verifier MCP. Use the TEST_VERIFIER_CONFIG env var to point both servers at the same config path.",
 	})
 
 	registerRegisterTool(server)
+	registerFingerprintTool(server)
+	registerDetectTool(server)
+	registerImportBundleTool(server)
+	registerHealthTool(server)
+	return server
+}
+
+func main() {
+	limit, err := registrationLimitFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	registrationLimit = limit
+	allowedCommands = allowedCommandsFromEnv()
 
+	server := newServer()
 	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
 		log.Printf("server failed: %v", err)
 	}
@@ -64,97 +322,423 @@ func registerRegisterTool(server *mcp.Server) {
 		Name:        toolRegister,
 		Description: "Register the command used to run tests. Provide the command as an array; the first entry is the executable and remaining entries are args.",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, args registerArgs) (*mcp.CallToolResult, registerResult, error) {
-		command, err := validateCommand(args.Command)
+		cfg, warnings, err := newStoredConfig(args)
 		if err != nil {
 			return nil, registerResult{}, err
 		}
-		env, err := validateEnv(args.Env)
+
+		cfgPath, err := configPath(args.ConfigPath)
 		if err != nil {
 			return nil, registerResult{}, err
 		}
-		if args.WorkingDir != "" {
-			info, statErr := os.Stat(args.WorkingDir)
-			if statErr != nil {
-				return nil, registerResult{}, fmt.Errorf("working_dir does not exist: %w", statErr)
+
+		var changes []configChange
+		if args.DryRun {
+			if changes, err = previewChanges(cfgPath, cfg); err != nil {
+				return nil, registerResult{}, err
 			}
-			if !info.IsDir() {
-				return nil, registerResult{}, fmt.Errorf("working_dir is not a directory: %s", args.WorkingDir)
+		} else {
+			if err := checkRegistrationRate(); err != nil {
+				return nil, registerResult{}, err
+			}
+			if err := writeConfig(cfgPath, cfg); err != nil {
+				return nil, registerResult{}, err
 			}
 		}
-
-		cfgPath, err := configPath()
+		fingerprint, err := configFingerprint(cfg)
 		if err != nil {
 			return nil, registerResult{}, err
 		}
 
-		cfg := storedConfig{
-			Command:    command,
-			WorkingDir: args.WorkingDir,
-			Env:        env,
-			UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+		message := "Test command registered. The test-verifier MCP can now run tests."
+		if args.DryRun {
+			noun := "fields"
+			if len(changes) == 1 {
+				noun = "field"
+			}
+			message = fmt.Sprintf("Dry run: nothing was written. Registering would change %d %s of %s.", len(changes), noun, cfgPath)
+			if len(changes) == 0 {
+				message = fmt.Sprintf("Dry run: nothing was written. Registering would not change %s.", cfgPath)
+			}
 		}
-
-		if err := writeConfig(cfgPath, cfg); err != nil {
-			return nil, registerResult{}, err
+		for _, warning := range warnings {
+			message += " Warning: " + warning + "."
 		}
-
-		message := "Test command registered. The test-verifier MCP can now run tests."
 		result := registerResult{
 			ConfigPath: cfgPath,
 			Command:    cfg.Command,
 			WorkingDir: cfg.WorkingDir,
 			Env:        cfg.Env,
+			Nice:       cfg.Nice,
 			UpdatedAt:  cfg.UpdatedAt,
 			Message:    message,
+
+			ExitCodeMessages: cfg.ExitCodeMessages,
+			SuccessExitCodes: cfg.SuccessExitCodes,
+			Container:        cfg.Container,
+			FailureMarkers:   cfg.FailureMarkers,
+
+			OutputEncoding:    cfg.OutputEncoding,
+			NormalizeNewlines: cfg.NormalizeNewlines,
+			OutputFormat:      cfg.OutputFormat,
+
+			OutputFilters: cfg.OutputFilters,
+			CacheSources:  cfg.CacheSources,
+
+			InheritEnv:     cfg.InheritEnv,
+			InheritEnvKeys: cfg.InheritEnvKeys,
+
+			FailOnOutputPatterns: cfg.FailOnOutputPatterns,
+			NoTestsPatterns:      cfg.NoTestsPatterns,
+			FailOnNoTests:        cfg.FailOnNoTests,
+			ArgsFromFile:         cfg.ArgsFromFile,
+
+			ExpandGlobs:        cfg.ExpandGlobs,
+			FailUnmatchedGlobs: cfg.FailUnmatchedGlobs,
+
+			TemplateArgs:           cfg.TemplateArgs,
+			AllowUnsetTemplateVars: cfg.AllowUnsetTemplateVars,
+
+			GitHubReport: cfg.GitHubReport,
+			SmokeCommand: cfg.SmokeCommand,
+			KillLadder:   cfg.KillLadder,
+			EnsureDirs:   cfg.EnsureDirs,
+
+			TrackFileChanges: cfg.TrackFileChanges,
+
+			Hermetic:    cfg.Hermetic,
+			HermeticEnv: cfg.HermeticEnv,
+
+			SummaryCommand: cfg.SummaryCommand,
+
+			VerboseArgs: cfg.VerboseArgs,
+			RaceArgs:    cfg.RaceArgs,
+
+			FailFastFlag: cfg.FailFastFlag,
+
+			RunAsUser:  cfg.RunAsUser,
+			RunAsGroup: cfg.RunAsGroup,
+
+			AnomalyFactor: cfg.AnomalyFactor,
+
+			Fingerprint: fingerprint,
+
+			DryRun:  args.DryRun,
+			Changes: changes,
+		}
+		if args.DryRun {
+			result.Env = redactEnvValues(result.Env)
 		}
 		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
 	})
 }
 
+// newStoredConfig validates registration arguments and builds the config
+// that will be written for the verifier, along with any warnings for the caller.
+func newStoredConfig(args registerArgs) (storedConfig, []string, error) {
+	command, err := validateCommand(args.Command)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	var warnings []string
+	if tokens := shellTokens(command); len(tokens) > 0 {
+		if !args.AllowShellTokens {
+			return storedConfig{}, nil, fmt.Errorf("%s; set allow_shell_tokens to register it anyway", shellTokenWarning(tokens))
+		}
+		warnings = append(warnings, shellTokenWarning(tokens))
+	}
+	env, err := validateEnv(args.Env)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	exitCodeMessages, err := validateExitCodeMessages(args.ExitCodeMessages)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	successExitCodes, err := validateSuccessExitCodes(args.SuccessExitCodes)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	container, err := validateContainer(args.Container)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	runAsUser, runAsGroup := strings.TrimSpace(args.RunAsUser), strings.TrimSpace(args.RunAsGroup)
+	if runAsUser != "" || runAsGroup != "" {
+		if container != nil {
+			return storedConfig{}, nil, errors.New("run_as_user and run_as_group cannot be combined with container")
+		}
+		if _, _, err := lookupRunAs(runAsUser, runAsGroup); err != nil {
+			return storedConfig{}, nil, err
+		}
+	}
+	if args.AnomalyFactor != 0 && !(args.AnomalyFactor > 1) {
+		return storedConfig{}, nil, errors.New("anomaly_factor must be greater than 1, or 0 to turn anomaly detection off")
+	}
+	githubReport, err := validateGitHubReport(args.GitHubReport)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	killLadder, err := validateKillLadder(args.KillLadder)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	ensureDirs, err := validateEnsureDirs(args.EnsureDirs)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	if len(args.HermeticEnv) > 0 && !args.Hermetic {
+		return storedConfig{}, nil, errors.New("hermetic_env requires hermetic")
+	}
+	hermeticEnv, err := validateEnv(args.HermeticEnv)
+	if err != nil {
+		return storedConfig{}, nil, fmt.Errorf("hermetic_env: %w", err)
+	}
+	var smokeCommand []string
+	if len(args.SmokeCommand) > 0 {
+		if smokeCommand, err = validateCommand(args.SmokeCommand); err != nil {
+			return storedConfig{}, nil, fmt.Errorf("smoke_command: %w", err)
+		}
+	}
+	var summaryCommand []string
+	if len(args.SummaryCommand) > 0 {
+		if summaryCommand, err = validateCommand(args.SummaryCommand); err != nil {
+			return storedConfig{}, nil, fmt.Errorf("summary_command: %w", err)
+		}
+	}
+	var verboseArgs, raceArgs []string
+	if len(args.VerboseArgs) > 0 {
+		if verboseArgs, err = validateArgs(args.VerboseArgs); err != nil {
+			return storedConfig{}, nil, fmt.Errorf("verbose_args: %w", err)
+		}
+	}
+	if len(args.RaceArgs) > 0 {
+		if raceArgs, err = validateArgs(args.RaceArgs); err != nil {
+			return storedConfig{}, nil, fmt.Errorf("race_args: %w", err)
+		}
+	}
+	encoding, err := normalizeEncoding(args.OutputEncoding)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	outputFormat, err := normalizeOutputFormat(args.OutputFormat)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := validateFilters(args.OutputFilters); err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := validateCacheSources(args.CacheSources); err != nil {
+		return storedConfig{}, nil, err
+	}
+	inheritMode, err := validateInheritEnv(args.InheritEnv, args.InheritEnvKeys)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := validatePatterns("fail_on_output_patterns", args.FailOnOutputPatterns); err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := validatePatterns("no_tests_patterns", args.NoTestsPatterns); err != nil {
+		return storedConfig{}, nil, err
+	}
+	argsFromFile, err := expandHome(strings.TrimSpace(args.ArgsFromFile))
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	workingDir, err := expandHome(args.WorkingDir)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	if workingDir != "" {
+		info, statErr := os.Stat(workingDir)
+		if statErr != nil {
+			return storedConfig{}, nil, fmt.Errorf("working_dir does not exist: %w", statErr)
+		}
+		if !info.IsDir() {
+			return storedConfig{}, nil, fmt.Errorf("working_dir is not a directory: %s", workingDir)
+		}
+	}
+
+	return storedConfig{
+		Command:    command,
+		WorkingDir: workingDir,
+		Env:        env,
+		Nice:       clampNice(args.Nice),
+		UpdatedAt:  time.Now().UTC().Format(time.RFC3339),
+
+		ExitCodeMessages: exitCodeMessages,
+		SuccessExitCodes: successExitCodes,
+		Container:        container,
+		FailureMarkers:   validateFailureMarkers(args.FailureMarkers),
+
+		OutputEncoding:    encoding,
+		NormalizeNewlines: args.NormalizeNewlines,
+		OutputFormat:      outputFormat,
+
+		OutputFilters: args.OutputFilters,
+		CacheSources:  args.CacheSources,
+
+		InheritEnv:     inheritMode,
+		InheritEnvKeys: args.InheritEnvKeys,
+
+		FailOnOutputPatterns: args.FailOnOutputPatterns,
+		NoTestsPatterns:      args.NoTestsPatterns,
+		FailOnNoTests:        args.FailOnNoTests,
+		ArgsFromFile:         argsFromFile,
+
+		ExpandGlobs:        args.ExpandGlobs,
+		FailUnmatchedGlobs: args.FailUnmatchedGlobs,
+
+		TemplateArgs:           args.TemplateArgs,
+		AllowUnsetTemplateVars: args.AllowUnsetTemplateVars,
+
+		GitHubReport: githubReport,
+		SmokeCommand: smokeCommand,
+		KillLadder:   killLadder,
+		EnsureDirs:   ensureDirs,
+
+		TrackFileChanges: args.TrackFileChanges,
+
+		Hermetic:    args.Hermetic,
+		HermeticEnv: hermeticEnv,
+
+		SummaryCommand: summaryCommand,
+
+		VerboseArgs: verboseArgs,
+		RaceArgs:    raceArgs,
+
+		FailFastFlag: strings.TrimSpace(args.FailFastFlag),
+
+		RunAsUser:  runAsUser,
+		RunAsGroup: runAsGroup,
+
+		AnomalyFactor: args.AnomalyFactor,
+	}, warnings, nil
+}
+
 func writeConfig(path string, cfg storedConfig) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	data, err := marshalConfig(path, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
 	}
 
+	fileMode, dirMode, err := configModes()
+	if err != nil {
+		return err
+	}
+
 	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(dir, dirMode); err != nil {
 		return fmt.Errorf("failed to create config dir: %w", err)
 	}
 
 	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0600); err != nil {
-		return fmt.Errorf("failed to write temp config: %w", err)
+	if err := os.WriteFile(tmp, data, fileMode); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write temp config in %s: %w", dir, err)
+	}
+	// WriteFile's mode is filtered by the umask and ignored for an existing
+	// file, so set it explicitly.
+	if err := os.Chmod(tmp, fileMode); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to set config file mode: %w", err)
 	}
 
 	if err := os.Rename(tmp, path); err != nil {
-		_ = os.Remove(path)
-		renameErr := os.Rename(tmp, path)
-		if renameErr != nil {
+		// Some platforms refuse to rename over an existing file, so replace
+		// it explicitly and report whichever attempt failed last.
+		if _, statErr := os.Stat(path); statErr == nil {
+			if rmErr := os.Remove(path); rmErr == nil {
+				err = os.Rename(tmp, path)
+			}
+		}
+		if err != nil {
 			_ = os.Remove(tmp)
-			return fmt.Errorf("failed to move config into place: %w", err)
+			return fmt.Errorf("failed to move config into place at %s: %w", path, err)
 		}
 	}
 
 	return nil
 }
 
-func configPath() (string, error) {
-	path := strings.TrimSpace(os.Getenv(configEnvVar))
+// configPath returns the absolute config path: override when one is given,
+// otherwise TEST_VERIFIER_CONFIG, otherwise the nearest existing
+// .test-verifier/command.json between the current directory and its
+// repository root, otherwise that file in the current directory. An
+// override must stay inside the allowed roots.
+func configPath(override string) (string, error) {
+	path := strings.TrimSpace(override)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(configEnvVar))
+	}
 	if path == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
 			return "", err
 		}
-		path = filepath.Join(cwd, ".test-verifier", "command.json")
+		path = searchConfig(cwd)
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
 	}
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return "", err
 	}
+	if err := checkConfigPath(abs); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(override) != "" {
+		if err := checkAllowedConfigPath(abs); err != nil {
+			return "", err
+		}
+	}
 	return abs, nil
 }
 
+// searchConfig returns the first .test-verifier/command.json found in dir
+// or its parents up to the repository root, the nearest directory with a
+// .git entry. Outside a repository, or when none exists, it returns the
+// path in dir.
+func searchConfig(dir string) string {
+	fallback := filepath.Join(dir, ".test-verifier", "command.json")
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return fallback
+		}
+		root = parent
+	}
+	for {
+		candidate := filepath.Join(dir, ".test-verifier", "command.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if dir == root {
+			return fallback
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// expandHome replaces a leading "~" or "~/" in path with the user's home
+// directory. Other tildes, including "~user", are left alone.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot expand ~ in %q: %w", path, err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
 func validateCommand(command []string) ([]string, error) {
 	if len(command) == 0 {
 		return nil, fmt.Errorf("command must contain at least one element")
@@ -167,9 +751,62 @@ func validateCommand(command []string) ([]string, error) {
 		}
 		clean = append(clean, trimmed)
 	}
+	if err := checkCommandAllowed(clean); err != nil {
+		return nil, err
+	}
+	return clean, nil
+}
+
+// validateArgs cleans an argument list such as verbose_args. It has no
+// executable, so unlike validateCommand the allowlist does not apply.
+func validateArgs(args []string) ([]string, error) {
+	clean := make([]string, 0, len(args))
+	for _, arg := range args {
+		trimmed := strings.TrimSpace(arg)
+		if trimmed == "" {
+			return nil, fmt.Errorf("argument entries cannot be empty")
+		}
+		if strings.ContainsRune(trimmed, 0) {
+			return nil, fmt.Errorf("argument entries cannot contain NUL bytes")
+		}
+		clean = append(clean, trimmed)
+	}
 	return clean, nil
 }
 
+// shellOperators are tokens that only have meaning to a shell. In argv mode
+// they reach the program as literal arguments.
+var shellOperators = map[string]bool{
+	"&&": true, "||": true, "|": true, ";": true, "&": true,
+	">": true, ">>": true, "<": true, "2>": true, "2>&1": true,
+}
+
+// shellTokens returns the command entries that look like shell operators.
+func shellTokens(command []string) []string {
+	var found []string
+	for _, part := range command {
+		if shellOperators[part] {
+			found = append(found, part)
+		}
+	}
+	return found
+}
+
+func shellTokenWarning(tokens []string) string {
+	return fmt.Sprintf("command contains shell operators %q that are passed to the program literally, not interpreted; wrap it in a shell instead, e.g. [\"sh\",\"-c\",\"npm test && npm run lint\"] (or [\"cmd\",\"/c\",...] on Windows)", tokens)
+}
+
+// clampNice limits a nice level to the range accepted by setpriority(2).
+func clampNice(nice int) int {
+	if nice < minNice {
+		return minNice
+	}
+	if nice > maxNice {
+		return maxNice
+	}
+	return nice
+}
+
 func validateEnv(env []string) ([]string, error) {
 	if len(env) == 0 {
 		return nil, nil
@@ -184,7 +821,249 @@ func validateEnv(env []string) ([]string, error) {
 		if len(parts) != 2 || parts[0] == "" {
 			return nil, fmt.Errorf("env entries must be KEY=VALUE, got %q", entry)
 		}
+		if parts[1] == envFilePrefix {
+			return nil, fmt.Errorf("env entry %q references a file but gives no path; use KEY=@/path/to/file, or KEY=@@ for a literal @", entry)
+		}
 		clean = append(clean, trimmed)
 	}
 	return clean, nil
 }
+
+// validateExitCodeMessages checks that every key is a decimal exit code in
+// the range 0-255 and drops entries without a message.
+func validateExitCodeMessages(messages map[string]string) (map[string]string, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	clean := make(map[string]string, len(messages))
+	for key, message := range messages {
+		code, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("exit_code_messages keys must be exit codes between 0 and 255, got %q", key)
+		}
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+		clean[strconv.Itoa(code)] = message
+	}
+	return clean, nil
+}
+
+// validateSuccessExitCodes checks that every code is in the range 0-255 and
+// returns them sorted without duplicates.
+func validateSuccessExitCodes(codes []int) ([]int, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	seen := make(map[int]bool, len(codes))
+	clean := make([]int, 0, len(codes))
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return nil, fmt.Errorf("success_exit_codes must be exit codes between 0 and 255, got %d", code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			clean = append(clean, code)
+		}
+	}
+	sort.Ints(clean)
+	return clean, nil
+}
+
+// validateFailureMarkers trims the configured markers and drops empty ones.
+func validateFailureMarkers(markers []string) []string {
+	var clean []string
+	for _, marker := range markers {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			clean = append(clean, marker)
+		}
+	}
+	return clean
+}
+
+// normalizeEncoding maps accepted spellings of an output encoding to the
+// name the verifier expects. An empty value means UTF-8.
+func normalizeEncoding(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return "", nil
+	case "windows-1252", "cp1252":
+		return "windows-1252", nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return "iso-8859-1", nil
+	}
+	return "", fmt.Errorf("unsupported output_encoding %q (want utf-8, windows-1252 or iso-8859-1)", name)
+}
+
+// normalizeOutputFormat lowercases a supported output format. An empty
+// value means plain output.
+func normalizeOutputFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return "", nil
+	case "tap":
+		return "tap", nil
+	}
+	return "", fmt.Errorf("unsupported output_format %q (want tap)", name)
+}
+
+// validateFilters checks that every output filter has a pattern that
+// compiles, so the verifier will not reject the config later.
+func validateFilters(rules []filterRule) error {
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("output_filters[%d]: pattern is required", i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("output_filters[%d]: invalid pattern %q: %w", i, rule.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateCacheSources checks that every cache_sources glob is relative and
+// well formed, matching the verifier's rules.
+func validateCacheSources(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("cache_sources entries must not be empty")
+		}
+		if path.IsAbs(filepath.ToSlash(pattern)) || filepath.IsAbs(pattern) {
+			return fmt.Errorf("cache_sources pattern %q must be relative to working_dir", pattern)
+		}
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid cache_sources pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validatePatterns checks that every regexp of the named config field
+// compiles, so the verifier will not reject the config later.
+func validatePatterns(field string, patterns []string) error {
+	for i, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("%s[%d]: pattern is required", field, i)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s[%d]: invalid pattern %q: %w", field, i, pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateInheritEnv checks an inherit_env mode against its allowlist and
+// returns the mode in canonical form; empty stays empty (all).
+func validateInheritEnv(mode string, keys []string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "", "allowlist":
+		for _, key := range keys {
+			if key == "" || strings.ContainsAny(key, "= \t") {
+				return "", fmt.Errorf("invalid inherit_env_keys entry %q", key)
+			}
+		}
+		return mode, nil
+	case "all", "none":
+		if len(keys) > 0 {
+			return "", fmt.Errorf("inherit_env_keys requires inherit_env \"allowlist\", not %q", mode)
+		}
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported inherit_env %q (want all, none or allowlist)", mode)
+}
+
+var githubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+func validateGitHubReport(c *githubReportConfig) (*githubReportConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+	clean := &githubReportConfig{
+		Repo:     strings.TrimSpace(c.Repo),
+		Context:  strings.TrimSpace(c.Context),
+		TokenEnv: strings.TrimSpace(c.TokenEnv),
+	}
+	if !githubRepoPattern.MatchString(clean.Repo) {
+		return nil, fmt.Errorf("github_report repo must be owner/name, got %q", c.Repo)
+	}
+	return clean, nil
+}
+
+const (
+	maxKillLadderSteps = 4
+	maxKillWaitSeconds = 300
+)
+
+// killLadderSignals are the signals a kill_ladder rung may send; SIGKILL
+// always follows the last rung.
+var killLadderSignals = map[string]bool{"INT": true, "TERM": true, "HUP": true, "QUIT": true}
+
+// validateKillLadder checks every rung and returns the ladder with signal
+// names canonicalized to INT, TERM, HUP or QUIT.
+func validateKillLadder(steps []killStep) ([]killStep, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	if len(steps) > maxKillLadderSteps {
+		return nil, fmt.Errorf("kill_ladder has %d rungs; at most %d are allowed", len(steps), maxKillLadderSteps)
+	}
+	clean := make([]killStep, 0, len(steps))
+	for _, step := range steps {
+		name := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(step.Signal)), "SIG")
+		if !killLadderSignals[name] {
+			return nil, fmt.Errorf("kill_ladder signal %q is not one of INT, TERM, HUP or QUIT (SIGKILL always follows the last rung)", step.Signal)
+		}
+		if step.WaitSeconds < 0 || step.WaitSeconds > maxKillWaitSeconds {
+			return nil, fmt.Errorf("kill_ladder wait_seconds must be between 0 and %d, got %d", maxKillWaitSeconds, step.WaitSeconds)
+		}
+		clean = append(clean, killStep{Signal: name, WaitSeconds: step.WaitSeconds})
+	}
+	return clean, nil
+}
+
+// validateEnsureDirs checks that every ensure_dirs entry is a relative path
+// that stays inside working_dir and returns the entries cleaned.
+func validateEnsureDirs(dirs []string) ([]string, error) {
+	var clean []string
+	for _, dir := range dirs {
+		trimmed := strings.TrimSpace(dir)
+		if trimmed == "" {
+			return nil, fmt.Errorf("ensure_dirs entries must not be empty")
+		}
+		local := filepath.Clean(filepath.FromSlash(trimmed))
+		if !filepath.IsLocal(local) {
+			return nil, fmt.Errorf("ensure_dirs entry %q must be a relative path inside working_dir", dir)
+		}
+		clean = append(clean, filepath.ToSlash(local))
+	}
+	return clean, nil
+}
+
+func validateContainer(c *containerConfig) (*containerConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+	clean := &containerConfig{
+		Image:   strings.TrimSpace(c.Image),
+		Workdir: strings.TrimSpace(c.Workdir),
+	}
+	if clean.Image == "" {
+		return nil, fmt.Errorf("container image is required")
+	}
+	if clean.Workdir != "" && !strings.HasPrefix(clean.Workdir, "/") {
+		return nil, fmt.Errorf("container workdir must be an absolute path, got %q", clean.Workdir)
+	}
+	for _, mount := range c.Mounts {
+		mount = strings.TrimSpace(mount)
+		if mount == "" {
+			continue
+		}
+		if !strings.Contains(mount, ":") {
+			return nil, fmt.Errorf("container mounts must be HOST:CONTAINER, got %q", mount)
+		}
+		clean.Mounts = append(clean.Mounts, mount)
+	}
+	return clean, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares dir, which will hold the config at path, and
+		// returns the config expected there afterwards.
+		setup   func(t *testing.T, dir, path string) *storedConfig
+		wantErr string
+	}{
+		{
+			name: "new file",
+			setup: func(t *testing.T, dir, path string) *storedConfig {
+				return &storedConfig{Command: []string{"go", "test", "./..."}}
+			},
+		},
+		{
+			name: "destination already exists",
+			setup: func(t *testing.T, dir, path string) *storedConfig {
+				if err := os.WriteFile(path, []byte(`{"command":["old"]}`), 0600); err != nil {
+					t.Fatal(err)
+				}
+				return &storedConfig{Command: []string{"go", "test", "./..."}}
+			},
+		},
+		{
+			name: "directory not writable",
+			setup: func(t *testing.T, dir, path string) *storedConfig {
+				if runtime.GOOS == "windows" {
+					t.Skip("directory permissions do not block writes on Windows")
+				}
+				old := storedConfig{Command: []string{"old"}}
+				if err := writeConfig(path, old); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(dir, 0500); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { os.Chmod(dir, 0700) })
+				if probe, err := os.Create(filepath.Join(dir, "probe")); err == nil {
+					probe.Close()
+					t.Skip("directory permissions are not enforced for this user")
+				}
+				return &old
+			},
+			wantErr: "failed to write temp config in",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "config")
+			if err := os.Mkdir(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "command.json")
+			want := tt.setup(t, dir, path)
+
+			err := writeConfig(path, storedConfig{Command: []string{"go", "test", "./..."}})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("writeConfig: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("writeConfig error = %v, want one containing %q", err, tt.wantErr)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got storedConfig
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Command, want.Command) {
+				t.Errorf("config command = %q, want %q", got.Command, want.Command)
+			}
+			if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("temp config left behind: %v", err)
+			}
+		})
+	}
+}
+
+// TestWriteConfigRenameFallback puts a directory where the config goes, so
+// the first rename fails on every platform. An empty directory is replaced;
+// one that cannot be removed makes writeConfig fail with the path and
+// without leaving the temp config behind.
+func TestWriteConfigRenameFallback(t *testing.T) {
+	cfg := storedConfig{Command: []string{"go", "test", "./..."}}
+
+	path := filepath.Join(t.TempDir(), "command.json")
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(path, cfg); err != nil {
+		t.Fatalf("writeConfig over an empty directory: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got storedConfig
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Command, cfg.Command) {
+		t.Errorf("config command = %q, want %q", got.Command, cfg.Command)
+	}
+
+	path = filepath.Join(t.TempDir(), "command.json")
+	if err := os.MkdirAll(filepath.Join(path, "keep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	err = writeConfig(path, cfg)
+	if err == nil || !strings.Contains(err.Error(), "failed to move config into place at "+path) {
+		t.Errorf("writeConfig over a non-empty directory: error = %v, want one naming %s", err, path)
+	}
+	if _, err := os.Stat(filepath.Join(path, "keep")); err != nil {
+		t.Errorf("non-empty directory damaged: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp config left behind: %v", err)
+	}
+}
+
+// setHome points os.UserHomeDir at home for the rest of the test.
+func setHome(t *testing.T, home string) {
+	t.Helper()
+	switch runtime.GOOS {
+	case "windows":
+		t.Setenv("USERPROFILE", home)
+	case "plan9":
+		t.Setenv("home", home)
+	default:
+		t.Setenv("HOME", home)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~", home},
+		{"~/projects/app", filepath.Join(home, "projects", "app")},
+		{"/srv/app", "/srv/app"},
+		{"projects/~/app", "projects/~/app"},
+		{"~other/app", "~other/app"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := expandHome(tt.path)
+		if err != nil {
+			t.Errorf("expandHome(%q): %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandHome(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandHomeWithoutHome(t *testing.T) {
+	setHome(t, "")
+	if _, err := expandHome("~/projects/app"); err == nil || !strings.Contains(err.Error(), `cannot expand ~ in "~/projects/app"`) {
+		t.Errorf("expandHome error = %v, want one naming the path", err)
+	}
+	if got, err := expandHome("/srv/app"); err != nil || got != "/srv/app" {
+		t.Errorf("expandHome(/srv/app) = %q, %v; want the path unchanged", got, err)
+	}
+}
+
+func TestConfigPathExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	setHome(t, home)
+	t.Setenv(configEnvVar, "~/config/command.json")
+	got, err := configPath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "config", "command.json"); got != want {
+		t.Errorf("configPath() = %q, want %q", got, want)
+	}
+
+	setHome(t, "")
+	if _, err := configPath(""); err == nil {
+		t.Error("configPath() succeeded without a home directory")
+	}
+}
+
+// TestToggleArgsIgnoreAllowlist checks that verbose_args and race_args are
+// not mistaken for executables when the allowlist is on.
+func TestToggleArgsIgnoreAllowlist(t *testing.T) {
+	defer func(saved []string) { allowedCommands = saved }(allowedCommands)
+	allowedCommands = []string{"go"}
+
+	cfg, _, err := newStoredConfig(registerArgs{
+		Command:     []string{"go", "test", "./..."},
+		VerboseArgs: []string{"-v"},
+		RaceArgs:    []string{"-race"},
+	})
+	if err != nil {
+		t.Fatalf("newStoredConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.VerboseArgs, []string{"-v"}) || !reflect.DeepEqual(cfg.RaceArgs, []string{"-race"}) {
+		t.Errorf("verbose_args = %q, race_args = %q", cfg.VerboseArgs, cfg.RaceArgs)
+	}
+
+	if _, _, err := newStoredConfig(registerArgs{Command: []string{"go", "test"}, VerboseArgs: []string{"-v", " "}}); err == nil {
+		t.Error("empty verbose_args entry accepted")
+	}
+	if _, _, err := newStoredConfig(registerArgs{Command: []string{"npm", "test"}}); err == nil {
+		t.Error("command outside the allowlist accepted")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const allowedCommandsEnvVar = "TEST_VERIFIER_ALLOWED_COMMANDS"
+
+// allowedCommands is the executable allowlist read from
+// TEST_VERIFIER_ALLOWED_COMMANDS. Nil allows any command.
+var allowedCommands []string
+
+// allowedCommandsFromEnv reads the comma-separated allowlist. Unset or
+// empty allows any command.
+func allowedCommandsFromEnv() []string {
+	var allowed []string
+	for _, entry := range strings.Split(os.Getenv(allowedCommandsEnvVar), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			allowed = append(allowed, entry)
+		}
+	}
+	return allowed
+}
+
+// checkCommandAllowed refuses a command whose executable, the first entry
+// exactly as written, is not on the allowlist.
+func checkCommandAllowed(command []string) error {
+	if allowedCommands == nil || len(command) == 0 {
+		return nil
+	}
+	for _, allowed := range allowedCommands {
+		if command[0] == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("policy violation: executable %q is not allowed by %s (allowed: %s)", command[0], allowedCommandsEnvVar, strings.Join(allowedCommands, ", "))
+}
@@ -0,0 +1,77 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const registrationLimitEnvVar = "TEST_VERIFIER_MAX_REGISTRATIONS_PER_MINUTE"
+
+// tokenBucket allows bursts of up to capacity events and refills at
+// capacity per minute.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// registrationLimit throttles config writes. Nil means unlimited, the
+// default.
+var registrationLimit *tokenBucket
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{capacity: float64(perMinute), tokens: float64(perMinute), last: time.Now()}
+}
+
+// take uses up a token if one is available; otherwise it reports how long
+// until the next one is.
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	perSecond := b.capacity / 60
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / perSecond * float64(time.Second)), false
+}
+
+// registrationLimitFromEnv reads TEST_VERIFIER_MAX_REGISTRATIONS_PER_MINUTE.
+// Unset, empty or 0 disables the limit.
+func registrationLimitFromEnv() (*tokenBucket, error) {
+	v := strings.TrimSpace(os.Getenv(registrationLimitEnvVar))
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s %q: must be a non-negative number of registrations", registrationLimitEnvVar, v)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return newTokenBucket(n), nil
+}
+
+// checkRegistrationRate counts a config write against the limit and refuses
+// it when the limit is exhausted.
+func checkRegistrationRate() error {
+	if registrationLimit == nil {
+		return nil
+	}
+	wait, ok := registrationLimit.take(time.Now())
+	if !ok {
+		return fmt.Errorf("too many registrations: at most %d per minute are allowed (%s); try again in %s", int(registrationLimit.capacity), registrationLimitEnvVar, wait.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// lookupRunAs resolves a config's run_as_user and run_as_group, each a name
+// or a numeric ID, on this host. Empty values resolve to nil.
+func lookupRunAs(userName, groupName string) (*user.User, *user.Group, error) {
+	var u *user.User
+	var g *user.Group
+	var err error
+	if userName != "" {
+		if isNumericID(userName) {
+			u, err = user.LookupId(userName)
+		} else {
+			u, err = user.Lookup(userName)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("run_as_user %q does not resolve: %w", userName, err)
+		}
+	}
+	if groupName != "" {
+		if isNumericID(groupName) {
+			g, err = user.LookupGroupId(groupName)
+		} else {
+			g, err = user.LookupGroup(groupName)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("run_as_group %q does not resolve: %w", groupName, err)
+		}
+	}
+	return u, g, nil
+}
+
+func isNumericID(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const allowedRootsEnvVar = "TEST_VERIFIER_ALLOWED_ROOTS"
+
+// allowedRoots returns the symlink-resolved roots listed in
+// TEST_VERIFIER_ALLOWED_ROOTS (separated like PATH: ':' on Unix, ';' on
+// Windows). A nil result means the sandbox is disabled.
+func allowedRoots() ([]string, error) {
+	raw := strings.TrimSpace(os.Getenv(allowedRootsEnvVar))
+	if raw == "" {
+		return nil, nil
+	}
+	var roots []string
+	for _, entry := range filepath.SplitList(raw) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resolved, err := resolvePath(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", allowedRootsEnvVar, entry, err)
+		}
+		roots = append(roots, resolved)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%s is set but lists no directories", allowedRootsEnvVar)
+	}
+	return roots, nil
+}
+
+// checkAllowedConfigPath rejects a config file outside the allowed roots.
+// The file and its directory may not exist yet, so the nearest existing
+// ancestor is resolved instead.
+func checkAllowedConfigPath(path string) error {
+	roots, err := allowedRoots()
+	if err != nil || roots == nil {
+		return err
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return fmt.Errorf("cannot resolve config_path %q: %w", path, err)
+	}
+	for _, root := range roots {
+		if isWithin(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: config_path %q is outside the directories allowed by %s", os.ErrPermission, resolved, allowedRootsEnvVar)
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path
+// and appends the components that do not exist yet.
+func resolveExisting(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(abs)}, missing...)
+		abs = parent
+	}
+}
+
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
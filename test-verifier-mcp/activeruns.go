@@ -0,0 +1,158 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolListRuns = "list_runs"
+
+	finishedRunGraceEnvVar  = "TEST_VERIFIER_FINISHED_RUN_GRACE"
+	defaultFinishedRunGrace = time.Minute
+
+	runStateRunning  = "running"
+	runStateFinished = "finished"
+)
+
+// finishedRunGrace is how long list_runs keeps showing a run after it
+// finished (-finished-run-grace).
+var finishedRunGrace = defaultFinishedRunGrace
+
+type trackedRun struct {
+	id         string
+	configPath string
+	command    []string
+	labels     map[string]string
+	pid        int
+	started    time.Time
+
+	finished time.Time // zero while the command runs
+	exitCode int
+	success  bool
+}
+
+// activeRuns lists the commands started by run_tests, in start order, until
+// finishedRunGrace after they finish. Trace IDs may repeat, so entries are
+// not keyed by run ID.
+var activeRuns struct {
+	mu   sync.Mutex
+	runs []*trackedRun
+}
+
+// trackRun adds a started command to activeRuns. Call the returned function
+// with the run's result once it is known.
+func trackRun(id, configPath string, command []string, labels map[string]string, pid int, started time.Time) (finish func(runResult)) {
+	run := &trackedRun{id: id, configPath: configPath, command: command, labels: labels, pid: pid, started: started}
+	activeRuns.mu.Lock()
+	reapFinishedRuns(time.Now())
+	activeRuns.runs = append(activeRuns.runs, run)
+	activeRuns.mu.Unlock()
+
+	return func(result runResult) {
+		activeRuns.mu.Lock()
+		defer activeRuns.mu.Unlock()
+		run.finished = time.Now()
+		run.exitCode = result.ExitCode
+		run.success = result.Success
+	}
+}
+
+// reapFinishedRuns drops runs that finished more than finishedRunGrace ago.
+// The caller holds activeRuns.mu.
+func reapFinishedRuns(now time.Time) {
+	kept := activeRuns.runs[:0]
+	for _, run := range activeRuns.runs {
+		if run.finished.IsZero() || now.Sub(run.finished) <= finishedRunGrace {
+			kept = append(kept, run)
+		}
+	}
+	clear(activeRuns.runs[len(kept):])
+	activeRuns.runs = kept
+}
+
+type listRunsArgs struct {
+	RunningOnly bool `json:"running_only,omitempty" jsonschema:"Only list runs whose command is still running"`
+}
+
+type listedRun struct {
+	RunID      string            `json:"run_id"`
+	ConfigPath string            `json:"config_path"`
+	Command    []string          `json:"command"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	PID        int               `json:"pid,omitempty"`
+	State      string            `json:"state" jsonschema:"running or finished"`
+	StartedAt  string            `json:"started_at"`
+	ElapsedMs  int64             `json:"elapsed_ms" jsonschema:"Time since the start, or the run's duration once finished"`
+
+	// FinishedAt, ExitCode and Success are set for finished runs.
+	FinishedAt string `json:"finished_at,omitempty"`
+	ExitCode   *int   `json:"exit_code,omitempty"`
+	Success    *bool  `json:"success,omitempty"`
+}
+
+type listRunsResult struct {
+	Runs []listedRun `json:"runs"`
+}
+
+func registerListRunsTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolListRuns,
+		Description: fmt.Sprintf("List the test commands this verifier is running, oldest first, with run ID, config, command, start time, elapsed time and state. Finished runs stay listed for %s (-finished-run-grace); set running_only to hide them. Cached results never appear, since nothing runs.", finishedRunGrace),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args listRunsArgs) (*mcp.CallToolResult, listRunsResult, error) {
+		now := time.Now()
+		result := listRunsResult{Runs: []listedRun{}}
+		running, finished := 0, 0
+
+		activeRuns.mu.Lock()
+		reapFinishedRuns(now)
+		for _, run := range activeRuns.runs {
+			listed := listedRun{
+				RunID:      run.id,
+				ConfigPath: run.configPath,
+				Command:    run.command,
+				Labels:     run.labels,
+				PID:        run.pid,
+				State:      runStateRunning,
+				StartedAt:  formatTime(run.started),
+				ElapsedMs:  now.Sub(run.started).Milliseconds(),
+			}
+			if !run.finished.IsZero() {
+				exitCode, success := run.exitCode, run.success
+				listed.State = runStateFinished
+				listed.ElapsedMs = run.finished.Sub(run.started).Milliseconds()
+				listed.FinishedAt = formatTime(run.finished)
+				listed.ExitCode, listed.Success = &exitCode, &success
+				finished++
+			} else {
+				running++
+			}
+			if args.RunningOnly && listed.State != runStateRunning {
+				continue
+			}
+			result.Runs = append(result.Runs, listed)
+		}
+		activeRuns.mu.Unlock()
+
+		lines := []string{fmt.Sprintf("%d runs running, %d recently finished.", running, finished)}
+		if args.RunningOnly {
+			lines[0] = fmt.Sprintf("%d runs running.", running)
+		}
+		for _, run := range result.Runs {
+			line := fmt.Sprintf("- %s %s: %s, %s", run.RunID, run.State, strings.Join(run.Command, " "), time.Duration(run.ElapsedMs)*time.Millisecond)
+			if run.ExitCode != nil {
+				line += fmt.Sprintf(", exit code %d", *run.ExitCode)
+			}
+			lines = append(lines, line)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}}}, result, nil
+	})
+}
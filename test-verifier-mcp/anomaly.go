@@ -0,0 +1,85 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// minAnomalySamples is how many earlier successful runs of a config
+	// are needed before its runs are checked for anomalies.
+	minAnomalySamples = 3
+
+	// Differences below these floors are noise, however large the ratio.
+	minAnomalyDurationMs  = 1000
+	minAnomalyOutputBytes = 1 << 10
+)
+
+// anomaly is a metric of a run that is anomaly_factor times above or below
+// its recent baseline.
+type anomaly struct {
+	Metric   string  `json:"metric" jsonschema:"duration_ms or output_bytes"`
+	Value    int64   `json:"value"`
+	Baseline int64   `json:"baseline" jsonschema:"Median over recent successful, uncached runs of the same config"`
+	Ratio    float64 `json:"ratio" jsonschema:"value divided by baseline"`
+}
+
+func (a anomaly) describe() string {
+	direction := "above"
+	if a.Value < a.Baseline {
+		direction = "below"
+	}
+	return fmt.Sprintf("%s %d is %.2fx the recent median of %d, far %s normal", a.Metric, a.Value, a.Ratio, a.Baseline, direction)
+}
+
+func validateAnomalyFactor(factor float64) error {
+	if factor != 0 && !(factor > 1) {
+		return errors.New("anomaly_factor must be greater than 1, or 0 to turn anomaly detection off")
+	}
+	return nil
+}
+
+// detectAnomalies compares a finished run's duration and output size with
+// the medians of the recent successful, uncached runs of the same config in
+// the run history. A metric is flagged when it is at least factor times
+// above or below its median, so a suite that suddenly finishes in seconds
+// or crawls towards its timeout stands out. The run itself must not be in
+// the history yet.
+func detectAnomalies(result runResult, factor float64) []anomaly {
+	var durations, sizes []int64
+	for _, run := range recentRuns() {
+		if run.ConfigFingerprint == result.ConfigFingerprint && run.Success && !run.Cached {
+			durations = append(durations, run.DurationMs)
+			sizes = append(sizes, run.OutputBytes)
+		}
+	}
+	if len(durations) < minAnomalySamples {
+		return nil
+	}
+
+	var anomalies []anomaly
+	for _, metric := range []struct {
+		name    string
+		value   int64
+		samples []int64
+		floor   int64
+	}{
+		{"duration_ms", result.DurationMs, durations, minAnomalyDurationMs},
+		{"output_bytes", result.OutputBytes, sizes, minAnomalyOutputBytes},
+	} {
+		sort.Slice(metric.samples, func(i, j int) bool { return metric.samples[i] < metric.samples[j] })
+		baseline := percentile(metric.samples, 50)
+		if baseline <= 0 || max(metric.value, baseline) < metric.floor {
+			continue
+		}
+		ratio := float64(metric.value) / float64(baseline)
+		if ratio >= factor || ratio <= 1/factor {
+			anomalies = append(anomalies, anomaly{Metric: metric.name, Value: metric.value, Baseline: baseline, Ratio: ratio})
+		}
+	}
+	return anomalies
+}
@@ -0,0 +1,43 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// argsFileComment starts a comment line in an args_from_file manifest.
+const argsFileComment = "#"
+
+// readArgsFile returns the arguments listed in the manifest at path, one per
+// line. Blank lines and lines starting with # are skipped; a relative path is
+// resolved against dir. Under TEST_VERIFIER_ALLOWED_ROOTS the manifest must
+// be inside the allowed roots.
+func readArgsFile(path, dir string) ([]string, error) {
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	if err := checkAllowedArgsFile(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("args_from_file: %w", err)
+	}
+	var args []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, argsFileComment) {
+			continue
+		}
+		if strings.ContainsRune(line, 0) {
+			return nil, fmt.Errorf("args_from_file %s:%d: arguments cannot contain NUL bytes", path, i+1)
+		}
+		args = append(args, line)
+	}
+	return args, nil
+}
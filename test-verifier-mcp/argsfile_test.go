@@ -0,0 +1,43 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadArgsFileAllowedRoots(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	for _, dir := range []string{root, outside} {
+		if err := os.WriteFile(filepath.Join(dir, "args.txt"), []byte("# suites\n./a\n\n./b\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(allowedRootsEnvVar, root)
+
+	args, err := readArgsFile("args.txt", root)
+	if err != nil {
+		t.Fatalf("manifest inside the root: %v", err)
+	}
+	if want := []string{"./a", "./b"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+	if _, err := readArgsFile(filepath.Join(outside, "args.txt"), root); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("absolute manifest outside the roots: error = %v, want a permission error", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "args.txt"), filepath.Join(root, "link.txt")); err == nil {
+		if _, err := readArgsFile("link.txt", root); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("symlink out of the roots: error = %v, want a permission error", err)
+		}
+	}
+
+	t.Setenv(allowedRootsEnvVar, "")
+	if _, err := readArgsFile(filepath.Join(outside, "args.txt"), root); err != nil {
+		t.Errorf("without allowed roots: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	// binarySniffLen is how much of a stream's output is inspected to
+	// decide whether it is binary.
+	binarySniffLen = 8 << 10
+
+	// maxBinaryOutput caps the raw bytes of a binary stream returned,
+	// base64-encoded, in the result.
+	maxBinaryOutput = 1 << 20
+)
+
+// looksBinary reports whether p is probably not text output: it holds a NUL
+// byte, or more than a tenth of it is control bytes or, for UTF-8 output,
+// invalid UTF-8. Tabs, line endings, form feeds, backspaces and the escape
+// byte of ANSI colors count as text.
+func looksBinary(p []byte, encoding string) bool {
+	if len(p) == 0 {
+		return false
+	}
+	suspect := 0
+	for i := 0; i < len(p); {
+		c := p[i]
+		switch {
+		case c == 0:
+			return true
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\b' && c != 0x1b, c == 0x7f:
+			suspect++
+		case c >= utf8.RuneSelf && encoding == "":
+			r, size := utf8.DecodeRune(p[i:])
+			// A sequence cut at the end of p is not evidence either way.
+			if r == utf8.RuneError && size == 1 && utf8.FullRune(p[i:]) {
+				suspect++
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return suspect*10 > len(p)
+}
+
+// binaryOutput returns the text reported in place of a binary stream's
+// output and the output itself, base64-encoded and cut at maxBinaryOutput
+// bytes, plus a warning for the result.
+func binaryOutput(stream string, raw []byte) (text, encoded, warning string) {
+	text = fmt.Sprintf("[binary output: %d bytes, base64-encoded in %s_base64]", len(raw), stream)
+	warning = fmt.Sprintf("%s looks binary; it is returned base64-encoded in %s_base64", stream, stream)
+	if len(raw) > maxBinaryOutput {
+		raw = raw[:maxBinaryOutput]
+		warning += fmt.Sprintf(", cut to the first %d bytes", maxBinaryOutput)
+	}
+	return text, base64.StdEncoding.EncodeToString(raw), warning
+}
+
+// applyBinaryOutput replaces the output of the streams the run's line
+// limiters flagged as binary with their base64-encoded bytes.
+func applyBinaryOutput(result *runResult, stdout, stderr *lineLimiter, stdoutRaw, stderrRaw []byte) {
+	for _, stream := range []struct {
+		name    string
+		limiter *lineLimiter
+		raw     []byte
+		text    *string
+		encoded *string
+	}{
+		{"stdout", stdout, stdoutRaw, &result.Stdout, &result.StdoutBase64},
+		{"stderr", stderr, stderrRaw, &result.Stderr, &result.StderrBase64},
+	} {
+		if !stream.limiter.binary {
+			continue
+		}
+		var warning string
+		*stream.text, *stream.encoded, warning = binaryOutput(stream.name, stream.raw)
+		result.Warnings = append(result.Warnings, warning)
+		result.Binary = true
+	}
+}
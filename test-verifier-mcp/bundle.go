@@ -0,0 +1,230 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolExportBundle = "export_bundle"
+
+	// bundleSchemaVersion is bumped when the bundle layout changes in a way
+	// import_bundle must know about.
+	bundleSchemaVersion = 1
+
+	// maxRunHistory caps how many run summaries are kept for bundles.
+	maxRunHistory = 20
+)
+
+// runSummary is the outcome of one run, without its output.
+type runSummary struct {
+	RunID             string            `json:"run_id,omitempty"`
+	StartedAt         string            `json:"started_at,omitempty"`
+	ConfigFingerprint string            `json:"config_fingerprint,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	ExitCode          int               `json:"exit_code"`
+	Success           bool              `json:"success"`
+	TimedOut          bool              `json:"timed_out,omitempty"`
+	Cached            bool              `json:"cached,omitempty"`
+	DurationMs        int64             `json:"duration_ms"`
+	OutputBytes       int64             `json:"output_bytes,omitempty"`
+	FailureKind       string            `json:"failure_kind,omitempty"`
+	Error             string            `json:"error,omitempty"`
+	Git               *gitState         `json:"git,omitempty"`
+}
+
+// runHistory keeps summaries of the most recent runs in memory, oldest
+// first.
+var runHistory struct {
+	mu      sync.Mutex
+	entries []runSummary
+}
+
+func appendRunHistory(result runResult) {
+	runHistory.mu.Lock()
+	defer runHistory.mu.Unlock()
+	if len(runHistory.entries) >= maxRunHistory {
+		runHistory.entries = runHistory.entries[1:]
+	}
+	runHistory.entries = append(runHistory.entries, runSummary{
+		RunID:             result.RunID,
+		StartedAt:         result.StartedAt,
+		ConfigFingerprint: result.ConfigFingerprint,
+		Labels:            result.Labels,
+		ExitCode:          result.ExitCode,
+		Success:           result.Success,
+		TimedOut:          result.TimedOut,
+		Cached:            result.Cached,
+		DurationMs:        result.DurationMs,
+		OutputBytes:       result.OutputBytes,
+		FailureKind:       result.FailureKind,
+		Error:             result.Error,
+		Git:               result.Git,
+	})
+}
+
+// clearRunHistory drops all but the keepLast most recent run summaries and
+// returns how many it removed, or would remove when dryRun is set.
+func clearRunHistory(keepLast int, dryRun bool) int {
+	runHistory.mu.Lock()
+	defer runHistory.mu.Unlock()
+	if len(runHistory.entries) <= keepLast {
+		return 0
+	}
+	removed := len(runHistory.entries) - keepLast
+	if !dryRun {
+		runHistory.entries = append([]runSummary(nil), runHistory.entries[removed:]...)
+	}
+	return removed
+}
+
+func recentRuns() []runSummary {
+	runHistory.mu.Lock()
+	defer runHistory.mu.Unlock()
+	return append([]runSummary{}, runHistory.entries...)
+}
+
+// findRun returns the most recent run summary with the given run ID. A
+// caller-provided trace ID may have been reused by several runs.
+func findRun(id string) (runSummary, bool) {
+	runHistory.mu.Lock()
+	defer runHistory.mu.Unlock()
+	for i := len(runHistory.entries) - 1; i >= 0; i-- {
+		if runHistory.entries[i].RunID == id {
+			return runHistory.entries[i], true
+		}
+	}
+	return runSummary{}, false
+}
+
+// runHistoryFilter selects run summaries from the history.
+type runHistoryFilter struct {
+	// Labels must all be set on a run, with the same values.
+	Labels map[string]string
+	// Since drops runs started before it, unless it is zero.
+	Since time.Time
+	// SinceLastSuccess drops the most recent matching successful run and
+	// everything before it.
+	SinceLastSuccess bool
+}
+
+// parseSince parses the since argument of a history query; empty means no
+// limit.
+func parseSince(since string) (time.Time, error) {
+	since = strings.TrimSpace(since)
+	if since == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be an RFC3339 timestamp such as 2026-01-02T15:04:05Z, got %q", since)
+	}
+	return t, nil
+}
+
+// filterRuns returns the runs that match f, keeping their order.
+func filterRuns(runs []runSummary, f runHistoryFilter) []runSummary {
+	matched := []runSummary{}
+	for _, run := range runs {
+		if !hasLabels(run.Labels, f.Labels) {
+			continue
+		}
+		if !f.Since.IsZero() {
+			started, err := time.Parse(time.RFC3339Nano, run.StartedAt)
+			if err != nil || started.Before(f.Since) {
+				continue
+			}
+		}
+		matched = append(matched, run)
+	}
+	if f.SinceLastSuccess {
+		for i := len(matched) - 1; i >= 0; i-- {
+			if matched[i].Success {
+				matched = matched[i+1:]
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// hasLabels reports whether labels contains every key of want with the
+// same value.
+func hasLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+type exportBundleArgs struct {
+	ConfigPath       string            `json:"config_path,omitempty" jsonschema:"Optional config file to export instead of the server default (TEST_VERIFIER_CONFIG)"`
+	IncludeSecrets   bool              `json:"include_secrets,omitempty" jsonschema:"Include the values of secret-looking env variables (tokens, keys, passwords); by default they are replaced with [redacted]"`
+	Labels           map[string]string `json:"labels,omitempty" jsonschema:"Only include recent runs tagged with all of these labels, e.g. {\"suite\":\"nightly\"}"`
+	Since            string            `json:"since,omitempty" jsonschema:"Only include recent runs started at or after this RFC3339 timestamp"`
+	SinceLastSuccess bool              `json:"since_last_success,omitempty" jsonschema:"Only include recent runs after the last successful one (all of them when none succeeded)"`
+}
+
+// bundle is a portable snapshot of the registered setup, read back by the
+// registrar's import_bundle.
+type bundle struct {
+	SchemaVersion int          `json:"schema_version"`
+	ExportedAt    string       `json:"exported_at"`
+	ConfigPath    string       `json:"config_path"`
+	Fingerprint   string       `json:"fingerprint"`
+	Config        storedConfig `json:"config"`
+	RecentRuns    []runSummary `json:"recent_runs"`
+	// RedactedEnv names the env variables whose values were replaced.
+	RedactedEnv []string `json:"redacted_env,omitempty"`
+}
+
+func registerExportBundleTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolExportBundle,
+		Description: "Export the registered config, its fingerprint and summaries of the most recent runs as one JSON bundle, e.g. to attach to a bug report or to load on another machine with the registrar's import_bundle. Values of secret-looking env variables are redacted unless include_secrets is set.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args exportBundleArgs) (*mcp.CallToolResult, bundle, error) {
+		cfg, cfgPath, _, err := loadConfig(args.ConfigPath)
+		if err != nil {
+			return nil, bundle{}, err
+		}
+		fingerprint, err := configFingerprint(cfg)
+		if err != nil {
+			return nil, bundle{}, err
+		}
+		labels, err := validateLabels(args.Labels)
+		if err != nil {
+			return nil, bundle{}, err
+		}
+		since, err := parseSince(args.Since)
+		if err != nil {
+			return nil, bundle{}, err
+		}
+
+		result := bundle{
+			SchemaVersion: bundleSchemaVersion,
+			ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+			ConfigPath:    cfgPath,
+			Fingerprint:   fingerprint,
+			Config:        cfg,
+			RecentRuns:    filterRuns(recentRuns(), runHistoryFilter{Labels: labels, Since: since, SinceLastSuccess: args.SinceLastSuccess}),
+		}
+		if !args.IncludeSecrets {
+			result.Config.Env, result.RedactedEnv = redactEnv(cfg.Env)
+		}
+		message := fmt.Sprintf("Exported the config at %s with %d recent runs.", cfgPath, len(result.RecentRuns))
+		if len(result.RedactedEnv) > 0 {
+			message += fmt.Sprintf(" Redacted env: %s; pass include_secrets to keep the values.", strings.Join(result.RedactedEnv, ", "))
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recordRuns replaces the run history with runs, oldest first.
+func recordRuns(t *testing.T, runs ...runResult) {
+	t.Helper()
+	clearRunHistory(0, false)
+	t.Cleanup(func() { clearRunHistory(0, false) })
+	for _, run := range runs {
+		appendRunHistory(run)
+	}
+}
+
+// runIDs lists the run IDs of runs, in order.
+func runIDs(runs []runSummary) []string {
+	ids := []string{}
+	for _, run := range runs {
+		ids = append(ids, run.RunID)
+	}
+	return ids
+}
+
+func TestFilterRunsByLabels(t *testing.T) {
+	recordRuns(t,
+		runResult{RunID: "a", Labels: map[string]string{"suite": "nightly", "os": "linux"}},
+		runResult{RunID: "b", Labels: map[string]string{"suite": "smoke"}},
+		runResult{RunID: "c"},
+		runResult{RunID: "d", Labels: map[string]string{"suite": "nightly"}},
+	)
+	tests := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{nil, "[a b c d]"},
+		{map[string]string{"suite": "nightly"}, "[a d]"},
+		{map[string]string{"suite": "nightly", "os": "linux"}, "[a]"},
+		{map[string]string{"suite": ""}, "[]"},
+		{map[string]string{"team": "core"}, "[]"},
+	}
+	for _, tt := range tests {
+		got := filterRuns(recentRuns(), runHistoryFilter{Labels: tt.labels})
+		if ids := fmt.Sprint(runIDs(got)); ids != tt.want {
+			t.Errorf("labels %v: runs %s, want %s", tt.labels, ids, tt.want)
+		}
+	}
+}
+
+func TestFilterRunsSince(t *testing.T) {
+	nightly := map[string]string{"suite": "nightly"}
+	recordRuns(t,
+		runResult{RunID: "a", StartedAt: "2026-01-01T10:00:00Z", Success: true, Labels: nightly},
+		runResult{RunID: "b", StartedAt: "2026-01-02T10:00:00.5Z", Success: false, Labels: nightly},
+		runResult{RunID: "c", StartedAt: "2026-01-03T10:00:00Z", Success: true},
+		runResult{RunID: "d", StartedAt: "2026-01-04T10:00:00Z", Success: false, Labels: nightly},
+	)
+	tests := []struct {
+		name   string
+		filter runHistoryFilter
+		want   string
+	}{
+		{"since", runHistoryFilter{Since: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)}, "[b c d]"},
+		{"since with offset", runHistoryFilter{Since: time.Date(2026, 1, 3, 12, 0, 0, 0, time.FixedZone("", 2*3600))}, "[c d]"},
+		{"since the future", runHistoryFilter{Since: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}, "[]"},
+		{"since last success", runHistoryFilter{SinceLastSuccess: true}, "[d]"},
+		{"since last nightly success", runHistoryFilter{SinceLastSuccess: true, Labels: nightly}, "[b d]"},
+		{"since last success without one", runHistoryFilter{SinceLastSuccess: true, Labels: map[string]string{"suite": "smoke"}}, "[]"},
+	}
+	for _, tt := range tests {
+		got := filterRuns(recentRuns(), tt.filter)
+		if ids := fmt.Sprint(runIDs(got)); ids != tt.want {
+			t.Errorf("%s: runs %s, want %s", tt.name, ids, tt.want)
+		}
+	}
+
+	if _, err := parseSince("yesterday"); err == nil {
+		t.Error(`parseSince("yesterday") succeeded`)
+	}
+	if got, err := parseSince(""); err != nil || !got.IsZero() {
+		t.Errorf(`parseSince("") = %v, %v; want no limit`, got, err)
+	}
+}
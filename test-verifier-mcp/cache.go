@@ -0,0 +1,213 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	noCacheEnvVar   = "TEST_VERIFIER_NO_CACHE"
+	cacheTTLEnvVar  = "TEST_VERIFIER_CACHE_TTL"
+	defaultCacheTTL = time.Hour
+	maxCacheEntries = 64
+)
+
+// resultCacheDisabled turns result caching off for every run (-no-cache).
+var resultCacheDisabled bool
+
+// resultCacheTTL is how long a cached result stays fresh. Zero keeps results
+// until the sources or the config change.
+var resultCacheTTL = defaultCacheTTL
+
+type cachedResult struct {
+	result   runResult
+	storedAt time.Time
+}
+
+// resultCache holds successful runs in memory, keyed by cacheKey.
+var resultCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+// cacheKey identifies a run by config fingerprint, the hash of the files
+// matched by the config's cache_sources, the per-run argv and inherit_env,
+// and the env after @file and ${captured.NAME} values are resolved.
+func cacheKey(fingerprint, sourceHash string, cmdline, inherit, env []string) string {
+	env = append([]string(nil), env...)
+	sort.Strings(env)
+	h := sha256.New()
+	for _, part := range [][]string{{fingerprint, sourceHash}, cmdline, inherit, env} {
+		for _, s := range part {
+			fmt.Fprintf(h, "%d:%s", len(s), s)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func lookupCachedResult(key string) (runResult, bool) {
+	resultCache.mu.Lock()
+	defer resultCache.mu.Unlock()
+	entry, ok := resultCache.entries[key]
+	if !ok {
+		return runResult{}, false
+	}
+	if resultCacheTTL > 0 && time.Since(entry.storedAt) > resultCacheTTL {
+		delete(resultCache.entries, key)
+		return runResult{}, false
+	}
+	return entry.result, true
+}
+
+func storeCachedResult(key string, result runResult) {
+	resultCache.mu.Lock()
+	defer resultCache.mu.Unlock()
+	if resultCache.entries == nil {
+		resultCache.entries = make(map[string]cachedResult)
+	}
+	if len(resultCache.entries) >= maxCacheEntries {
+		var oldest string
+		for k, entry := range resultCache.entries {
+			if oldest == "" || entry.storedAt.Before(resultCache.entries[oldest].storedAt) {
+				oldest = k
+			}
+		}
+		delete(resultCache.entries, oldest)
+	}
+	resultCache.entries[key] = cachedResult{result: result, storedAt: time.Now()}
+}
+
+// clearCachedResults drops all but the keepLast most recently stored cache
+// entries and returns how many it removed, or would remove when dryRun is set.
+func clearCachedResults(keepLast int, dryRun bool) int {
+	resultCache.mu.Lock()
+	defer resultCache.mu.Unlock()
+	keys := make([]string, 0, len(resultCache.entries))
+	for key := range resultCache.entries {
+		keys = append(keys, key)
+	}
+	if len(keys) <= keepLast {
+		return 0
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return resultCache.entries[keys[i]].storedAt.After(resultCache.entries[keys[j]].storedAt)
+	})
+	stale := keys[keepLast:]
+	if !dryRun {
+		for _, key := range stale {
+			delete(resultCache.entries, key)
+		}
+	}
+	return len(stale)
+}
+
+func validateCacheSources(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("cache_sources entries must not be empty")
+		}
+		if path.IsAbs(filepath.ToSlash(pattern)) || filepath.IsAbs(pattern) {
+			return fmt.Errorf("cache_sources pattern %q must be relative to working_dir", pattern)
+		}
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid cache_sources pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// hashSources hashes the path and content of every file under dir matching
+// one of patterns. Patterns use path.Match syntax on slash-separated paths
+// relative to dir, plus "**" for any number of directories.
+func hashSources(dir string, patterns []string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	h := sha256.New()
+	matched := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchAnySource(patterns, rel) {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%d:%s", len(rel), rel)
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		h.Write([]byte{0})
+		matched++
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash cache_sources: %w", err)
+	}
+	if matched == 0 {
+		return "", errors.New("cache_sources matched no files")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func matchAnySource(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path segments against pattern segments, where a "**"
+// segment matches zero or more path segments.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
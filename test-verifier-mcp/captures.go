@@ -0,0 +1,171 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxCaptureVars      = 32
+	maxCapturedValueLen = 4 << 10
+)
+
+// captureName is the form of a capture_vars name.
+var captureName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// capturedRef matches ${captured.NAME} references in arguments and env
+// values.
+var capturedRef = regexp.MustCompile(`\$\{captured\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// capturedVars holds the values captured by earlier runs, in memory, per
+// MCP session, so one client's captures are neither visible to nor
+// overwritten by another's. A session's values are dropped when it ends.
+// Calls without a session share the nil entry.
+var capturedVars struct {
+	mu       sync.Mutex
+	sessions map[*mcp.ServerSession]map[string]string
+}
+
+// captureSession returns the session whose captures a call reads and writes.
+func captureSession(req *mcp.CallToolRequest) *mcp.ServerSession {
+	if req == nil {
+		return nil
+	}
+	return req.Session
+}
+
+// validateCaptureVars compiles the capture_vars patterns of a run.
+func validateCaptureVars(vars map[string]string) (map[string]*regexp.Regexp, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	if len(vars) > maxCaptureVars {
+		return nil, fmt.Errorf("capture_vars may have at most %d entries", maxCaptureVars)
+	}
+	compiled := make(map[string]*regexp.Regexp, len(vars))
+	for name, pattern := range vars {
+		if !captureName.MatchString(name) {
+			return nil, fmt.Errorf("capture_vars name %q must be a letter or underscore followed by letters, digits or underscores", name)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("capture_vars %s: invalid pattern: %w", name, err)
+		}
+		compiled[name] = re
+	}
+	return compiled, nil
+}
+
+// captureOutput matches each pattern against stdout, then stderr, and
+// stores the first capture group of the first match, or the whole match
+// for a pattern without groups, for later runs of session. It returns the
+// captured values and the names of patterns that matched nothing.
+func captureOutput(session *mcp.ServerSession, patterns map[string]*regexp.Regexp, stdout, stderr string) (map[string]string, []string) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	captured := make(map[string]string)
+	var unmatched []string
+	for name, re := range patterns {
+		value, ok := "", false
+		for _, output := range []string{stdout, stderr} {
+			if m := re.FindStringSubmatch(output); m != nil {
+				value, ok = m[0], true
+				if len(m) > 1 {
+					value = m[1]
+				}
+				break
+			}
+		}
+		if !ok {
+			unmatched = append(unmatched, name)
+			continue
+		}
+		if len(value) > maxCapturedValueLen {
+			value = value[:maxCapturedValueLen]
+		}
+		captured[name] = value
+	}
+	sort.Strings(unmatched)
+
+	if len(captured) == 0 {
+		return captured, unmatched
+	}
+	capturedVars.mu.Lock()
+	defer capturedVars.mu.Unlock()
+	if capturedVars.sessions == nil {
+		capturedVars.sessions = make(map[*mcp.ServerSession]map[string]string)
+	}
+	values, ok := capturedVars.sessions[session]
+	if !ok {
+		values = make(map[string]string)
+		capturedVars.sessions[session] = values
+		if session != nil {
+			go forgetCaptures(session)
+		}
+	}
+	for name, value := range captured {
+		values[name] = value
+	}
+	return captured, unmatched
+}
+
+// forgetCaptures drops a session's captured values once it ends.
+func forgetCaptures(session *mcp.ServerSession) {
+	session.Wait()
+	capturedVars.mu.Lock()
+	defer capturedVars.mu.Unlock()
+	delete(capturedVars.sessions, session)
+}
+
+func appendCaptureWarning(warnings, unmatched []string) []string {
+	if len(unmatched) == 0 {
+		return warnings
+	}
+	return append(warnings, fmt.Sprintf("capture_vars matched nothing for %s; earlier values are kept", strings.Join(unmatched, ", ")))
+}
+
+// expandCapturedArgs replaces ${captured.NAME} in each argument with the
+// value an earlier run of session captured as NAME.
+func expandCapturedArgs(session *mcp.ServerSession, argv []string) ([]string, error) {
+	return expandCaptured(session, argv, func(arg string) string { return fmt.Sprintf("command argument %q", arg) })
+}
+
+// expandCapturedEnv does the same for the values of KEY=VALUE entries. Errors
+// name only the variable, since values may come from secret files.
+func expandCapturedEnv(session *mcp.ServerSession, env []string) ([]string, error) {
+	return expandCaptured(session, env, func(entry string) string {
+		key, _, _ := strings.Cut(entry, "=")
+		return "env variable " + key
+	})
+}
+
+func expandCaptured(session *mcp.ServerSession, values []string, describe func(string) string) ([]string, error) {
+	capturedVars.mu.Lock()
+	defer capturedVars.mu.Unlock()
+	captured := capturedVars.sessions[session]
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		var missing string
+		expanded[i] = capturedRef.ReplaceAllStringFunc(value, func(ref string) string {
+			name := capturedRef.FindStringSubmatch(ref)[1]
+			v, ok := captured[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("%s references ${captured.%s}, which no run in this session has captured yet", describe(value), missing)
+		}
+	}
+	return expanded, nil
+}
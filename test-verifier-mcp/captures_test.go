@@ -0,0 +1,54 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TestCapturesPerSession checks that a value captured in one session can be
+// used by its later runs but not by another client's session.
+func TestCapturesPerSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses echo as the test command")
+	}
+	cfgPath := filepath.Join(t.TempDir(), "command.json")
+	if err := writeConfig(cfgPath, storedConfig{Command: []string{"echo", "built", "out/app"}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configEnvVar, cfgPath)
+	t.Setenv(allowRootEnvVar, "1")
+	t.Setenv(noGitEnvVar, "1")
+	invalidateConfigCache()
+
+	server := newServer("")
+	first, second := connect(t, server), connect(t, server)
+
+	var captured runResult
+	callTool(t, first, toolRun, map[string]any{"capture_vars": map[string]string{"ARTIFACT": `built (\S+)`}}, &captured)
+	if got := captured.Captured["ARTIFACT"]; got != "out/app" {
+		t.Fatalf("captured ARTIFACT = %q, want out/app", got)
+	}
+
+	useCapture := map[string]any{"extra_args": []string{"${captured.ARTIFACT}"}}
+	var reused runResult
+	callTool(t, first, toolRun, useCapture, &reused)
+	if got := strings.TrimSpace(reused.Stdout); got != "built out/app out/app" {
+		t.Errorf("stdout in the capturing session = %q, want the captured value appended", got)
+	}
+
+	res, err := second.CallTool(context.Background(), &mcp.CallToolParams{Name: toolRun, Arguments: useCapture})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Errorf("another session could use the capture: %v", res.Content)
+	}
+}
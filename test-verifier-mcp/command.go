@@ -0,0 +1,45 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolRunCommand = "run_command"
+
+type runCommandArgs struct {
+	Command        []string `json:"command" jsonschema:"Command and arguments to run once, e.g. [\"go\",\"vet\",\"./...\"]"`
+	WorkingDir     string   `json:"working_dir,omitempty" jsonschema:"Optional working directory for the command"`
+	Env            []string `json:"env,omitempty" jsonschema:"Optional environment variables as KEY=VALUE"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty" jsonschema:"Optional timeout in seconds (default 600)"`
+}
+
+func registerRunCommandTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolRunCommand,
+		Description: "Run a one-off command with the same capture, timeout and result handling as run_tests, without reading or changing the registered test command.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args runCommandArgs) (*mcp.CallToolResult, runResult, error) {
+		command, err := validateCommand(args.Command)
+		if err != nil {
+			return nil, runResult{}, err
+		}
+		// The env becomes the config's env, which reads @path values, so
+		// refuse them here as for run_tests; @@ is unescaped later.
+		if _, err := unescapeRunEnv(args.Env); err != nil {
+			return nil, runResult{}, err
+		}
+		cfg, err := validateConfig(storedConfig{
+			Command:    command,
+			WorkingDir: args.WorkingDir,
+			Env:        args.Env,
+		})
+		if err != nil {
+			return nil, runResult{}, err
+		}
+		return runConfig(ctx, req, cfg, "", false, runArgs{TimeoutSeconds: args.TimeoutSeconds}, nil)
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	maxRunsEnvVar      = "TEST_VERIFIER_MAX_CONCURRENT_RUNS"
+	queueTimeoutEnvVar = "TEST_VERIFIER_RUN_QUEUE_TIMEOUT"
+)
+
+// runSlots limits how many test commands run at once across all sessions.
+// A nil channel means there is no limit.
+var runSlots chan struct{}
+
+// runQueueTimeout is how long a run waits for a free slot before giving up.
+// Zero rejects runs immediately when every slot is taken.
+var runQueueTimeout time.Duration
+
+var errAtCapacity = errors.New("test-verifier is at capacity")
+
+func setMaxConcurrentRuns(n int) {
+	if n > 0 {
+		runSlots = make(chan struct{}, n)
+	}
+}
+
+// acquireRunSlot reserves a run slot, queueing for up to runQueueTimeout. It
+// returns the function that frees the slot and how long the caller waited.
+func acquireRunSlot(ctx context.Context) (release func(), waited time.Duration, err error) {
+	if runSlots == nil {
+		return func() {}, 0, nil
+	}
+	release = func() { <-runSlots }
+	select {
+	case runSlots <- struct{}{}:
+		return release, 0, nil
+	default:
+	}
+	if runQueueTimeout <= 0 {
+		return nil, 0, fmt.Errorf("%w: all %d run slots are in use", errAtCapacity, cap(runSlots))
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(runQueueTimeout)
+	defer timer.Stop()
+	select {
+	case runSlots <- struct{}{}:
+		return release, time.Since(start), nil
+	case <-timer.C:
+		return nil, time.Since(start), fmt.Errorf("%w: none of the %d run slots freed up within %s", errAtCapacity, cap(runSlots), runQueueTimeout)
+	case <-ctx.Done():
+		return nil, time.Since(start), ctx.Err()
+	}
+}
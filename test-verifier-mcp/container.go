@@ -0,0 +1,118 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultContainerWorkdir = "/workspace"
+	containerKillTimeout    = 10 * time.Second
+)
+
+// containerConfig runs the registered command inside a Docker container
+// instead of directly on the host.
+type containerConfig struct {
+	Image   string   `json:"image" jsonschema:"Container image to run the command in, e.g. golang:1.23"`
+	Mounts  []string `json:"mounts,omitempty" jsonschema:"Extra bind mounts as HOST:CONTAINER[:OPTIONS]"`
+	Workdir string   `json:"workdir,omitempty" jsonschema:"Path inside the container where the working directory is mounted (default /workspace)"`
+}
+
+type containerRun struct {
+	Image string `json:"image"`
+	Name  string `json:"name"`
+}
+
+func validateContainer(c *containerConfig) (*containerConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+	clean := &containerConfig{
+		Image:   strings.TrimSpace(c.Image),
+		Workdir: strings.TrimSpace(c.Workdir),
+	}
+	if clean.Image == "" {
+		return nil, fmt.Errorf("container image is required")
+	}
+	if clean.Workdir != "" && !strings.HasPrefix(clean.Workdir, "/") {
+		return nil, fmt.Errorf("container workdir must be an absolute path, got %q", clean.Workdir)
+	}
+	for _, mount := range c.Mounts {
+		mount = strings.TrimSpace(mount)
+		if mount == "" {
+			continue
+		}
+		if !strings.Contains(mount, ":") {
+			return nil, fmt.Errorf("container mounts must be HOST:CONTAINER, got %q", mount)
+		}
+		clean.Mounts = append(clean.Mounts, mount)
+	}
+	return clean, nil
+}
+
+// containerCommand wraps cmdline in a docker run invocation that mounts
+// hostDir as the container's working directory. Env values are passed by
+// name only (-e KEY) so they are read from the docker CLI's environment
+// rather than appearing on its command line.
+func containerCommand(c *containerConfig, name, hostDir string, env []string, cmdline []string) []string {
+	workdir := c.Workdir
+	if workdir == "" {
+		workdir = defaultContainerWorkdir
+	}
+	args := []string{"docker", "run", "--rm", "--name", name, "-v", hostDir + ":" + workdir, "-w", workdir}
+	for _, mount := range c.Mounts {
+		args = append(args, "-v", mount)
+	}
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		args = append(args, "-e", key)
+	}
+	args = append(args, c.Image)
+	return append(args, cmdline...)
+}
+
+// containerHostDir returns the absolute host directory to mount.
+func containerHostDir(workingDir string) (string, error) {
+	if workingDir == "" {
+		return os.Getwd()
+	}
+	return filepath.Abs(workingDir)
+}
+
+// mountSource returns the host path of a HOST:CONTAINER[:OPTIONS] mount.
+// It reports false for a named volume, whose source is not a path.
+func mountSource(mount string) (string, bool) {
+	source := mount
+	// Keep a Windows drive letter, as in C:\src:/src, with the path.
+	if len(source) > 2 && source[1] == ':' && (source[2] == '\\' || source[2] == '/') {
+		if i := strings.Index(source[2:], ":"); i >= 0 {
+			source = source[:2+i]
+		}
+	} else {
+		source, _, _ = strings.Cut(source, ":")
+	}
+	if !strings.ContainsAny(source, `/\`) && !strings.HasPrefix(source, ".") {
+		return "", false
+	}
+	return source, true
+}
+
+func containerName() string {
+	return fmt.Sprintf("test-verifier-%d-%d", os.Getpid(), time.Now().UnixNano())
+}
+
+// killContainer stops a container started by run_tests. Killing only the
+// docker CLI would leave the container running.
+func killContainer(docker, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerKillTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, docker, "kill", name).Run()
+}
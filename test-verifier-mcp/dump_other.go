@@ -0,0 +1,22 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+)
+
+var errDumpUnsupported = errors.New("SIGQUIT goroutine dumps are only supported on Unix")
+
+func prepareDump(cmd *exec.Cmd) error {
+	return errDumpUnsupported
+}
+
+func dumpAndKill(cmd *exec.Cmd, grace time.Duration) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,36 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// prepareDump starts the command in its own process group so the timeout
+// dump reaches every process it spawns, such as the test binary under
+// "go test".
+func prepareDump(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return nil
+}
+
+// dumpAndKill sends SIGQUIT to the command's process group, so Go programs
+// print their goroutine stacks to stderr, and kills the group after grace.
+func dumpAndKill(cmd *exec.Cmd, grace time.Duration) error {
+	pgid := cmd.Process.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGQUIT); err != nil {
+		return cmd.Process.Kill()
+	}
+	time.AfterFunc(grace, func() {
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	})
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported values for storedConfig.OutputEncoding besides the default UTF-8.
+const (
+	encodingCP1252 = "windows-1252"
+	encodingLatin1 = "iso-8859-1"
+)
+
+const replacementChar = "\uFFFD"
+
+// cp1252High maps bytes 0x80-0x9F of Windows-1252 to Unicode. Zero entries
+// are undefined in the code page; every other byte matches ISO-8859-1.
+var cp1252High = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+// normalizeEncoding maps accepted spellings of an output encoding to its
+// canonical name. An empty value means UTF-8.
+func normalizeEncoding(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return "", nil
+	case "windows-1252", "cp1252":
+		return encodingCP1252, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return encodingLatin1, nil
+	}
+	return "", fmt.Errorf("unsupported output_encoding %q (want utf-8, windows-1252 or iso-8859-1)", name)
+}
+
+// decodeOutput converts captured output to valid UTF-8. UTF-8 output has
+// invalid bytes replaced with U+FFFD; single-byte encodings are transcoded.
+// When normalizeNewlines is set, CRLF line endings become LF.
+func decodeOutput(raw []byte, encoding string, normalizeNewlines bool) string {
+	var out string
+	switch encoding {
+	case encodingCP1252, encodingLatin1:
+		var b strings.Builder
+		b.Grow(len(raw))
+		for _, c := range raw {
+			r := rune(c)
+			if encoding == encodingCP1252 && c >= 0x80 && c < 0xA0 {
+				if r = cp1252High[c-0x80]; r == 0 {
+					b.WriteString(replacementChar)
+					continue
+				}
+			}
+			b.WriteRune(r)
+		}
+		out = b.String()
+	default:
+		out = string(raw)
+		if !utf8.ValidString(out) {
+			out = strings.ToValidUTF8(out, replacementChar)
+		}
+	}
+	if normalizeNewlines {
+		out = strings.ReplaceAll(out, "\r\n", "\n")
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validateEnsureDirs checks that every ensure_dirs entry is a relative path
+// that stays inside working_dir and returns the entries cleaned.
+func validateEnsureDirs(dirs []string) ([]string, error) {
+	var clean []string
+	for _, dir := range dirs {
+		trimmed := strings.TrimSpace(dir)
+		if trimmed == "" {
+			return nil, errors.New("ensure_dirs entries must not be empty")
+		}
+		local := filepath.Clean(filepath.FromSlash(trimmed))
+		if !filepath.IsLocal(local) {
+			return nil, fmt.Errorf("ensure_dirs entry %q must be a relative path inside working_dir", dir)
+		}
+		clean = append(clean, filepath.ToSlash(local))
+	}
+	return clean, nil
+}
+
+// createEnsureDirs creates the ensure_dirs entries that do not exist yet
+// under workingDir and returns them. Symlinks are resolved so an entry
+// cannot reach outside workingDir through one.
+func createEnsureDirs(workingDir string, dirs []string) ([]string, error) {
+	if workingDir == "" {
+		workingDir = "."
+	}
+	root, err := resolvePath(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("ensure_dirs: %w", err)
+	}
+	var created []string
+	for _, dir := range dirs {
+		path := filepath.Join(workingDir, filepath.FromSlash(dir))
+		resolved, err := resolveExisting(path)
+		if err != nil {
+			return nil, fmt.Errorf("ensure_dirs: %w", err)
+		}
+		if !isWithin(root, resolved) {
+			return nil, fmt.Errorf("ensure_dirs: %q resolves to %s, outside working_dir", dir, resolved)
+		}
+		if info, err := os.Stat(path); err == nil {
+			if !info.IsDir() {
+				return nil, fmt.Errorf("ensure_dirs: %s exists and is not a directory", path)
+			}
+			continue
+		}
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return nil, fmt.Errorf("ensure_dirs: %w", err)
+		}
+		created = append(created, dir)
+	}
+	return created, nil
+}
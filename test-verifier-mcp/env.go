@@ -0,0 +1,257 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolShowRunEnv = "show_run_env"
+	redactedValue  = "[redacted]"
+
+	// envFilePrefix marks an env value as a file to read the value from,
+	// e.g. GITHUB_TOKEN=@/run/secrets/gh. A doubled prefix escapes a
+	// literal leading @.
+	envFilePrefix = "@"
+
+	inheritAll       = "all"
+	inheritNone      = "none"
+	inheritAllowlist = "allowlist"
+)
+
+// secretKeyParts mark environment variables whose values are hidden by
+// show_run_env.
+var secretKeyParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "API_KEY", "APIKEY", "PRIVATE_KEY", "CREDENTIAL", "AUTH"}
+
+type showRunEnvArgs struct {
+	Env        []string `json:"env,omitempty" jsonschema:"Extra run environment variables (KEY=VALUE), as they would be passed to run_tests"`
+	ConfigPath string   `json:"config_path,omitempty" jsonschema:"Optional config file to use instead of the server default (TEST_VERIFIER_CONFIG)"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty" jsonschema:"Override the registered inherit_env mode, as run_tests would: all, none or allowlist"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty" jsonschema:"Server environment variables to pass through in allowlist mode"`
+}
+
+type showRunEnvResult struct {
+	ConfigPath string   `json:"config_path"`
+	Env        []string `json:"env"`
+	Redacted   []string `json:"redacted,omitempty"`
+	Message    string   `json:"message"`
+	InheritEnv string   `json:"inherit_env"`
+}
+
+func registerShowRunEnvTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolShowRunEnv,
+		Description: "Show the environment run_tests would give the test command: the inherited part of the server's environment (see inherit_env), then the registered env, then the per-run env, later entries winning. Values of secret-looking variables are redacted.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args showRunEnvArgs) (*mcp.CallToolResult, showRunEnvResult, error) {
+		cfg, cfgPath, _, err := loadConfig(args.ConfigPath)
+		if err != nil {
+			return nil, showRunEnvResult{}, err
+		}
+		runEnv, err := validateEnv(args.Env)
+		if err != nil {
+			return nil, showRunEnvResult{}, err
+		}
+
+		mode, keys, err := effectiveInheritEnv(cfg, args.InheritEnv, args.InheritEnvKeys)
+		if err != nil {
+			return nil, showRunEnvResult{}, err
+		}
+
+		env, redacted := redactEnv(mergeEnv(inheritedEnv(mode, keys), cfg.hermeticEnv(), cfg.Env, runEnv))
+		sort.Strings(env)
+		message := fmt.Sprintf("The test command would run with %d environment variables (%d redacted).", len(env), len(redacted))
+		if cfg.Container != nil {
+			message += " In container mode only the registered and per-run variables are forwarded into the container; the rest is the docker client's environment."
+		}
+		result := showRunEnvResult{
+			ConfigPath: cfgPath,
+			Env:        env,
+			Redacted:   redacted,
+			Message:    message,
+			InheritEnv: mode,
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
+
+// mergeEnv combines KEY=VALUE layers into one environment in which each key
+// appears once; a key set by a later layer overrides earlier ones but keeps
+// its original position. Keys compare case-insensitively on Windows.
+func mergeEnv(layers ...[]string) []string {
+	var merged []string
+	index := make(map[string]int)
+	for _, layer := range layers {
+		for _, entry := range layer {
+			key, _, _ := strings.Cut(entry, "=")
+			if runtime.GOOS == "windows" {
+				key = strings.ToUpper(key)
+			}
+			if i, ok := index[key]; ok {
+				merged[i] = entry
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+// normalizeInheritEnv validates an inherit_env mode and its allowlist. An
+// empty mode means all, or allowlist when keys are given.
+func normalizeInheritEnv(mode string, keys []string) (string, []string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = inheritAll
+		if len(keys) > 0 {
+			mode = inheritAllowlist
+		}
+	}
+	switch mode {
+	case inheritAll, inheritNone:
+		if len(keys) > 0 {
+			return "", nil, fmt.Errorf("inherit_env_keys requires inherit_env %q, not %q", inheritAllowlist, mode)
+		}
+		return mode, nil, nil
+	case inheritAllowlist:
+		for _, key := range keys {
+			if key == "" || strings.ContainsAny(key, "= \t") {
+				return "", nil, fmt.Errorf("invalid inherit_env_keys entry %q", key)
+			}
+		}
+		return mode, keys, nil
+	}
+	return "", nil, fmt.Errorf("unsupported inherit_env %q (want all, none or allowlist)", mode)
+}
+
+// effectiveInheritEnv returns the run's inheritance mode: the per-call
+// override when one is given, otherwise the registered one.
+func effectiveInheritEnv(cfg storedConfig, mode string, keys []string) (string, []string, error) {
+	if mode == "" && len(keys) == 0 {
+		mode, keys = cfg.InheritEnv, cfg.InheritEnvKeys
+	}
+	return normalizeInheritEnv(mode, keys)
+}
+
+// inheritedEnv returns the part of the server's environment a run starts
+// from. Allowlisted keys compare case-insensitively on Windows.
+func inheritedEnv(mode string, keys []string) []string {
+	switch mode {
+	case inheritNone:
+		return nil
+	case inheritAllowlist:
+		allowed := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			if runtime.GOOS == "windows" {
+				key = strings.ToUpper(key)
+			}
+			allowed[key] = true
+		}
+		var env []string
+		for _, entry := range os.Environ() {
+			key, _, _ := strings.Cut(entry, "=")
+			if runtime.GOOS == "windows" {
+				key = strings.ToUpper(key)
+			}
+			if allowed[key] {
+				env = append(env, entry)
+			}
+		}
+		return env
+	}
+	return os.Environ()
+}
+
+// resolveEnvFiles replaces KEY=@path entries with the contents of path,
+// minus one trailing newline, and unescapes KEY=@@value to KEY=@value.
+func resolveEnvFiles(env []string) ([]string, error) {
+	var resolved []string
+	for i, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(value, envFilePrefix) {
+			continue
+		}
+		if resolved == nil {
+			resolved = append([]string{}, env...)
+		}
+		if strings.HasPrefix(value, envFilePrefix+envFilePrefix) {
+			resolved[i] = key + "=" + value[len(envFilePrefix):]
+			continue
+		}
+		path, err := expandHome(value[len(envFilePrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", key, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: cannot read value from file: %w", key, err)
+		}
+		content := strings.TrimSuffix(string(data), "\n")
+		resolved[i] = key + "=" + strings.TrimSuffix(content, "\r")
+	}
+	if resolved == nil {
+		return env, nil
+	}
+	return resolved, nil
+}
+
+// unescapeRunEnv unescapes KEY=@@value in per-run env and refuses KEY=@path.
+// Only the registered config may read values from files, so a caller of
+// run_tests cannot pull arbitrary files the server can read into a run.
+func unescapeRunEnv(env []string) ([]string, error) {
+	var unescaped []string
+	for i, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(value, envFilePrefix) {
+			continue
+		}
+		if !strings.HasPrefix(value, envFilePrefix+envFilePrefix) {
+			return nil, fmt.Errorf("env %s: only the registered config's env may read values from files with @path; write @@ for a value that starts with @", key)
+		}
+		if unescaped == nil {
+			unescaped = append([]string{}, env...)
+		}
+		unescaped[i] = key + "=" + value[len(envFilePrefix):]
+	}
+	if unescaped == nil {
+		return env, nil
+	}
+	return unescaped, nil
+}
+
+// redactEnv hides the values of secret-looking variables and returns the
+// names it redacted.
+func redactEnv(env []string) ([]string, []string) {
+	out := make([]string, 0, len(env))
+	var redacted []string
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		if isSecretKey(key) {
+			out = append(out, key+"="+redactedValue)
+			redacted = append(redacted, key)
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out, redacted
+}
+
+func isSecretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
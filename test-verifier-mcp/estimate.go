@@ -0,0 +1,97 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolEstimateDuration = "estimate_duration"
+
+	// defaultMinEstimateSamples is how many successful runs an estimate
+	// needs unless the caller asks for another minimum.
+	defaultMinEstimateSamples = 3
+)
+
+type estimateDurationArgs struct {
+	ConfigPath string `json:"config_path,omitempty" jsonschema:"Optional config file to estimate instead of the server default (TEST_VERIFIER_CONFIG)"`
+	MinSamples int    `json:"min_samples,omitempty" jsonschema:"Successful runs needed before estimating (default 3)"`
+}
+
+type estimateDurationResult struct {
+	ConfigPath        string `json:"config_path"`
+	ConfigFingerprint string `json:"config_fingerprint"`
+	// SampleSize is the number of successful, uncached runs of this config
+	// in the recent history; the durations are set only when it reaches
+	// the minimum.
+	SampleSize int   `json:"sample_size"`
+	MedianMs   int64 `json:"median_ms,omitempty"`
+	P90Ms      int64 `json:"p90_ms,omitempty"`
+	MinMs      int64 `json:"min_ms,omitempty"`
+	MaxMs      int64 `json:"max_ms,omitempty"`
+	Sufficient bool  `json:"sufficient"`
+}
+
+func registerEstimateDurationTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolEstimateDuration,
+		Description: fmt.Sprintf("Estimate how long run_tests will take from the durations of recent successful runs of the same config (the last %d runs are kept in memory): median and p90, with the sample size. Runs nothing.", maxRunHistory),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args estimateDurationArgs) (*mcp.CallToolResult, estimateDurationResult, error) {
+		if args.MinSamples < 0 {
+			return nil, estimateDurationResult{}, fmt.Errorf("min_samples must not be negative")
+		}
+		minSamples := args.MinSamples
+		if minSamples == 0 {
+			minSamples = defaultMinEstimateSamples
+		}
+		cfg, cfgPath, _, err := loadConfig(args.ConfigPath)
+		if err != nil {
+			return nil, estimateDurationResult{}, err
+		}
+		fingerprint, err := configFingerprint(cfg)
+		if err != nil {
+			return nil, estimateDurationResult{}, err
+		}
+
+		// Cached results report the duration of the run they reuse, so
+		// they would count it twice.
+		var durations []int64
+		for _, run := range recentRuns() {
+			if run.ConfigFingerprint == fingerprint && run.Success && !run.Cached {
+				durations = append(durations, run.DurationMs)
+			}
+		}
+		result := estimateDurationResult{
+			ConfigPath:        cfgPath,
+			ConfigFingerprint: fingerprint,
+			SampleSize:        len(durations),
+		}
+		if len(durations) < minSamples {
+			message := fmt.Sprintf("Not enough history to estimate: %d successful runs of this config, %d needed.", len(durations), minSamples)
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+		}
+
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		result.Sufficient = true
+		result.MedianMs = percentile(durations, 50)
+		result.P90Ms = percentile(durations, 90)
+		result.MinMs, result.MaxMs = durations[0], durations[len(durations)-1]
+		message := fmt.Sprintf("From %d successful runs: median %d ms, p90 %d ms (range %d-%d ms).", result.SampleSize, result.MedianMs, result.P90Ms, result.MinMs, result.MaxMs)
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,124 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// failureExcerptLines caps how many lines a failure excerpt contains.
+	failureExcerptLines = 20
+
+	failureKindOutputPattern = "output_pattern"
+	failureKindNoTests       = "no_tests"
+)
+
+// defaultFailureMarkers are the substrings that usually open a failure
+// report in common test runners' output.
+var defaultFailureMarkers = []string{"FAIL", "Error:", "panic:", "AssertionError", "✕"}
+
+// defaultNoTestsPatterns match the "nothing was run" messages of pytest, Jest
+// and Vitest.
+var defaultNoTestsPatterns = []string{`no tests ran`, `No tests found`, `No test files found`}
+
+var defaultNoTests = mustCompilePatterns(defaultNoTestsPatterns)
+
+// failureExcerpt returns the first failureExcerptLines lines starting at the
+// first line of stdout, then stderr, that contains one of markers. When no
+// line matches it falls back to the tail of stderr.
+func failureExcerpt(stdout, stderr string, markers []string) string {
+	if len(markers) == 0 {
+		markers = defaultFailureMarkers
+	}
+	for _, output := range []string{stdout, stderr} {
+		lines := strings.Split(output, "\n")
+		for i, line := range lines {
+			if containsAny(line, markers) {
+				end := min(i+failureExcerptLines, len(lines))
+				return strings.TrimRight(strings.Join(lines[i:end], "\n"), "\n")
+			}
+		}
+	}
+	lines := strings.Split(strings.TrimRight(stderr, "\n"), "\n")
+	start := max(len(lines)-failureExcerptLines, 0)
+	return strings.Join(lines[start:], "\n")
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFailureMarkers trims the configured markers and drops empty ones.
+func validateFailureMarkers(markers []string) []string {
+	var clean []string
+	for _, marker := range markers {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			clean = append(clean, marker)
+		}
+	}
+	return clean
+}
+
+// compilePatterns compiles the regexps of the named config field, in order.
+func compilePatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for i, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("%s[%d]: pattern is required", field, i)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: invalid pattern %q: %w", field, i, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchFailPattern reports the first fail_on_output_patterns entry that
+// matches stdout or stderr.
+func (cfg storedConfig) matchFailPattern(stdout, stderr string) (string, bool) {
+	return matchPatterns(cfg.failPatterns, cfg.FailOnOutputPatterns, stdout, stderr)
+}
+
+// noTestsRan reports whether the output says that no tests were run, using
+// the config's no_tests_patterns or the defaults.
+func (cfg storedConfig) noTestsRan(stdout, stderr string) bool {
+	compiled, patterns := cfg.noTestsPatterns, cfg.NoTestsPatterns
+	if len(patterns) == 0 {
+		compiled, patterns = defaultNoTests, defaultNoTestsPatterns
+	}
+	_, ok := matchPatterns(compiled, patterns, stdout, stderr)
+	return ok
+}
+
+// matchPatterns returns the source of the first compiled regexp that matches
+// stdout or stderr.
+func matchPatterns(compiled []*regexp.Regexp, patterns []string, stdout, stderr string) (string, bool) {
+	for i, re := range compiled {
+		if re.MatchString(stdout) || re.MatchString(stderr) {
+			return patterns[i], true
+		}
+	}
+	return "", false
+}
+
+func mustCompilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
@@ -0,0 +1,143 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxTrackedFiles caps how many files track_file_changes hashes per
+// snapshot; beyond it the comparison is partial and marked truncated.
+const maxTrackedFiles = 10000
+
+type trackedFile struct {
+	hash    string
+	modTime time.Time
+}
+
+// fileSnapshot maps slash-separated paths relative to the snapshot root to
+// their content hash.
+type fileSnapshot struct {
+	files     map[string]trackedFile
+	truncated bool
+}
+
+// fileChanges lists the paths under working_dir a run created, modified or
+// deleted.
+type fileChanges struct {
+	Created  []string `json:"created,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	Deleted  []string `json:"deleted,omitempty"`
+	// Truncated is set when working_dir held more than maxTrackedFiles
+	// files, so only part of it was compared.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// snapshotFiles hashes the regular files under dir, skipping .git
+// directories, up to maxTrackedFiles.
+func snapshotFiles(dir string) (fileSnapshot, error) {
+	if dir == "" {
+		dir = "."
+	}
+	snap := fileSnapshot{files: make(map[string]trackedFile)}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p != dir && errors.Is(err, fs.ErrNotExist) {
+				// Removed while we walked.
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(snap.files) >= maxTrackedFiles {
+			snap.truncated = true
+			return filepath.SkipAll
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		file, err := hashFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		snap.files[filepath.ToSlash(rel)] = file
+		return nil
+	})
+	if err != nil {
+		return fileSnapshot{}, err
+	}
+	return snap, nil
+}
+
+func hashFile(path string) (trackedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return trackedFile{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return trackedFile{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return trackedFile{}, err
+	}
+	return trackedFile{hash: hex.EncodeToString(h.Sum(nil)), modTime: info.ModTime()}, nil
+}
+
+// diffSnapshots compares snapshots of dir taken before and after a run that
+// started at start. When a snapshot was truncated, a path missing from it
+// may simply not have been reached, so a deletion is confirmed on disk and
+// a creation by a modification time after start.
+func diffSnapshots(dir string, before, after fileSnapshot, start time.Time) *fileChanges {
+	if dir == "" {
+		dir = "."
+	}
+	changes := &fileChanges{Truncated: before.truncated || after.truncated}
+	for path, file := range after.files {
+		old, ok := before.files[path]
+		switch {
+		case !ok && (!before.truncated || !file.modTime.Before(start)):
+			changes.Created = append(changes.Created, path)
+		case ok && old.hash != file.hash:
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range before.files {
+		if _, ok := after.files[path]; ok {
+			continue
+		}
+		if after.truncated {
+			if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(path))); err == nil {
+				continue
+			}
+		}
+		changes.Deleted = append(changes.Deleted, path)
+	}
+	sort.Strings(changes.Created)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Deleted)
+	return changes
+}
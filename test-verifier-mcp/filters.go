@@ -0,0 +1,43 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// filterRule rewrites captured output before it is returned, e.g. to strip
+// absolute paths or timestamps so results diff cleanly.
+type filterRule struct {
+	Pattern     string `json:"pattern" jsonschema:"Go regular expression to match in stdout and stderr"`
+	Replacement string `json:"replacement,omitempty" jsonschema:"Replacement text; $1 and ${name} expand to capture groups"`
+}
+
+// compileFilters compiles every rule's pattern, in order.
+func compileFilters(rules []filterRule) ([]*regexp.Regexp, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	compiled := make([]*regexp.Regexp, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("output_filters[%d]: pattern is required", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("output_filters[%d]: invalid pattern %q: %w", i, rule.Pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// filterOutput applies the config's compiled output filters to s in order.
+func (cfg storedConfig) filterOutput(s string) string {
+	for i, re := range cfg.filters {
+		s = re.ReplaceAllString(s, cfg.OutputFilters[i].Replacement)
+	}
+	return s
+}
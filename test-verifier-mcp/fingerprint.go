@@ -0,0 +1,26 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// configFingerprint hashes the canonical JSON form of cfg: UpdatedAt is
+// dropped and env entries are sorted, so only meaningful changes alter it.
+func configFingerprint(cfg storedConfig) (string, error) {
+	cfg.UpdatedAt = ""
+	cfg.Env = append([]string(nil), cfg.Env...)
+	sort.Strings(cfg.Env)
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	githubAPIURLEnvVar   = "TEST_VERIFIER_GITHUB_API_URL"
+	defaultGitHubAPIURL  = "https://api.github.com"
+	defaultGitHubContext = "test-verifier"
+	githubRequestTimeout = 15 * time.Second
+
+	// maxStatusDescription is GitHub's limit on a commit status description.
+	maxStatusDescription = 140
+)
+
+// defaultGitHubTokenKeys are looked up, in order, when github_report names no
+// token_env.
+var defaultGitHubTokenKeys = []string{"GITHUB_TOKEN", "GITHUB_PERSONAL_ACCESS_TOKEN"}
+
+var githubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// githubSHAPattern matches an abbreviated or full SHA-1 or SHA-256 commit
+// hash.
+var githubSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,64}$`)
+
+// githubReportConfig says where report_to_github sets commit statuses.
+type githubReportConfig struct {
+	Repo     string `json:"repo" jsonschema:"Repository as owner/name"`
+	Context  string `json:"context,omitempty" jsonschema:"Name of the status check (default test-verifier)"`
+	TokenEnv string `json:"token_env,omitempty" jsonschema:"Environment variable holding the GitHub token, looked up in the registered env and then the verifier's environment (default GITHUB_TOKEN, then GITHUB_PERSONAL_ACCESS_TOKEN)"`
+}
+
+// githubStatus reports the commit status set for a run.
+type githubStatus struct {
+	Repo    string `json:"repo"`
+	SHA     string `json:"sha,omitempty"`
+	Context string `json:"context"`
+	State   string `json:"state"`
+	Error   string `json:"error,omitempty"`
+}
+
+func validateGitHubReport(c *githubReportConfig) (*githubReportConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+	clean := &githubReportConfig{
+		Repo:     strings.TrimSpace(c.Repo),
+		Context:  strings.TrimSpace(c.Context),
+		TokenEnv: strings.TrimSpace(c.TokenEnv),
+	}
+	if !githubRepoPattern.MatchString(clean.Repo) {
+		return nil, fmt.Errorf("github_report repo must be owner/name, got %q", c.Repo)
+	}
+	return clean, nil
+}
+
+// reportToGitHub sets a commit status for the outcome of a run. Failures are
+// returned in the status's Error and never affect the run.
+func reportToGitHub(ctx context.Context, cfg storedConfig, env []string, sha string, result runResult, summary string) *githubStatus {
+	report := cfg.GitHubReport
+	status := &githubStatus{Repo: report.Repo, Context: report.Context, State: githubState(result)}
+	if status.Context == "" {
+		status.Context = defaultGitHubContext
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, githubRequestTimeout)
+	defer cancel()
+
+	token := githubToken(report.TokenEnv, env)
+	if token == "" {
+		keys := defaultGitHubTokenKeys
+		if report.TokenEnv != "" {
+			keys = []string{report.TokenEnv}
+		}
+		status.Error = fmt.Sprintf("no GitHub token: set %s", strings.Join(keys, " or "))
+		return status
+	}
+	sha = strings.TrimSpace(sha)
+	if sha == "" {
+		head, err := gitHead(ctx, cfg.WorkingDir)
+		if err != nil {
+			status.Error = fmt.Sprintf("cannot determine the commit (pass github_sha): %v", err)
+			return status
+		}
+		sha = head
+	}
+	status.SHA = sha
+	if !githubSHAPattern.MatchString(sha) {
+		status.Error = fmt.Sprintf("github_sha must be a hexadecimal commit hash, got %q", sha)
+		return status
+	}
+
+	if err := postCommitStatus(ctx, token, report.Repo, sha, status.State, status.Context, summary); err != nil {
+		status.Error = strings.ReplaceAll(err.Error(), token, redactedValue)
+	}
+	return status
+}
+
+// reportRun sets the commit status for result and records the outcome in
+// it, adding a warning when the status could not be set.
+func reportRun(ctx context.Context, cfg storedConfig, env []string, sha string, result *runResult, summary string) {
+	result.GitHubStatus = reportToGitHub(ctx, cfg, env, sha, *result, summary)
+	if result.GitHubStatus.Error != "" {
+		result.Warnings = append(result.Warnings, "GitHub status not set: "+result.GitHubStatus.Error)
+	}
+}
+
+func githubState(result runResult) string {
+	switch {
+	case result.Success:
+		return "success"
+	case result.TimedOut, result.ExitCode == -1 && result.Error != "":
+		return "error"
+	default:
+		return "failure"
+	}
+}
+
+// githubToken looks up the token in env, falling back to the verifier's own
+// environment.
+func githubToken(tokenEnv string, env []string) string {
+	keys := defaultGitHubTokenKeys
+	if tokenEnv != "" {
+		keys = []string{tokenEnv}
+	}
+	merged := mergeEnv(os.Environ(), env)
+	for _, key := range keys {
+		for _, entry := range merged {
+			if k, v, _ := strings.Cut(entry, "="); k == key && v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func gitHead(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func postCommitStatus(ctx context.Context, token, repo, sha, state, statusContext, description string) error {
+	if !githubSHAPattern.MatchString(sha) {
+		return fmt.Errorf("invalid commit hash %q", sha)
+	}
+	if runes := []rune(description); len(runes) > maxStatusDescription {
+		description = string(runes[:maxStatusDescription-1]) + "…"
+	}
+	body, err := json.Marshal(map[string]string{"state": state, "context": statusContext, "description": description})
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSpace(os.Getenv(githubAPIURLEnvVar))
+	if base == "" {
+		base = defaultGitHubAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/statuses/%s", strings.TrimSuffix(base, "/"), repo, url.PathEscape(sha))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("GitHub API returned %s: %s", resp.Status, apiErr.Message)
+	}
+	return errors.New("GitHub API returned " + resp.Status)
+}
@@ -0,0 +1,43 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportToGitHubValidatesSHA(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	t.Setenv(githubAPIURLEnvVar, server.URL)
+
+	cfg := storedConfig{GitHubReport: &githubReportConfig{Repo: "owner/app"}}
+	env := []string{"GITHUB_TOKEN=secret"}
+	result := runResult{Success: true}
+	for _, sha := range []string{"../../../user", "abc123", "deadbeef?x=1", "0123456789abcdef/statuses"} {
+		status := reportToGitHub(context.Background(), cfg, env, sha, result, "ok")
+		if status.Error == "" || !strings.Contains(status.Error, "github_sha") {
+			t.Errorf("github_sha %q: error = %q, want it refused", sha, status.Error)
+		}
+	}
+	if len(paths) != 0 {
+		t.Fatalf("requests sent for invalid hashes: %q", paths)
+	}
+
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	if status := reportToGitHub(context.Background(), cfg, env, sha, result, "ok"); status.Error != "" {
+		t.Fatalf("valid hash: %s", status.Error)
+	}
+	if want := "/repos/owner/app/statuses/" + sha; len(paths) != 1 || paths[0] != want {
+		t.Errorf("request paths = %q, want [%q]", paths, want)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	noGitEnvVar     = "TEST_VERIFIER_NO_GIT"
+	gitStateTimeout = 5 * time.Second
+)
+
+// gitStateDisabled skips the git calls that record the code state of each
+// run (-no-git).
+var gitStateDisabled bool
+
+// gitState is the code a run was made against.
+type gitState struct {
+	Commit string `json:"commit"`
+	// Dirty is set when the tree has uncommitted or untracked changes.
+	Dirty bool `json:"dirty"`
+}
+
+// collectGitState returns HEAD and the dirty state of the repository
+// containing dir, or nil if dir is not in a git repository or git fails.
+func collectGitState(ctx context.Context, dir string) *gitState {
+	if gitStateDisabled {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, gitStateTimeout)
+	defer cancel()
+	commit, err := gitHead(ctx, dir)
+	if err != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return &gitState{Commit: commit, Dirty: strings.TrimSpace(string(out)) != ""}
+}
@@ -0,0 +1,121 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// globMeta are the characters that make an argument a glob pattern.
+const globMeta = "*?["
+
+// expandGlobs replaces every argument after argv[0] that looks like a glob
+// with the paths it matches under dir, sorted. Flags (arguments starting with
+// -) are never expanded. A pattern without matches is kept as a literal
+// unless strict is set.
+func expandGlobs(argv []string, dir string, strict bool) ([]string, error) {
+	if len(argv) == 0 {
+		return argv, nil
+	}
+	expanded := []string{argv[0]}
+	for _, arg := range argv[1:] {
+		if strings.HasPrefix(arg, "-") || !strings.ContainsAny(arg, globMeta) {
+			expanded = append(expanded, arg)
+			continue
+		}
+		matches, err := expandGlob(arg, dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			if strict {
+				return nil, fmt.Errorf("glob %q matched no files", arg)
+			}
+			expanded = append(expanded, arg)
+			continue
+		}
+		expanded = append(expanded, matches...)
+	}
+	return expanded, nil
+}
+
+// expandGlob returns the paths matching pattern, relative to dir when the
+// pattern is relative. "**" matches any number of directories.
+func expandGlob(pattern, dir string) ([]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	base := dir
+	if filepath.IsAbs(pattern) {
+		base = ""
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+
+	var matches []string
+	if !strings.Contains(pattern, "**") {
+		found, err := filepath.Glob(filepath.Join(base, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		for _, match := range found {
+			if base != "" {
+				if rel, err := filepath.Rel(base, match); err == nil {
+					match = rel
+				}
+			}
+			matches = append(matches, match)
+		}
+		return matches, nil
+	}
+
+	// Walk from the longest directory prefix without metacharacters.
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	static := 0
+	for static < len(segments)-1 && !strings.ContainsAny(segments[static], globMeta) {
+		static++
+	}
+	root := filepath.Join(base, filepath.FromSlash(strings.Join(segments[:static], "/")))
+	if filepath.IsAbs(pattern) && !filepath.IsAbs(root) {
+		root = string(filepath.Separator)
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return fs.SkipAll
+			}
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		if matchGlob(segments[static:], strings.Split(filepath.ToSlash(rel), "/")) {
+			match := p
+			if base != "" {
+				if r, err := filepath.Rel(base, p); err == nil {
+					match = r
+				}
+			}
+			matches = append(matches, match)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand glob %q: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolHealth = "health"
+
+	serverName    = "test-verifier"
+	serverVersion = "0.1.0"
+)
+
+type healthArgs struct{}
+
+type healthResult struct {
+	Server        string `json:"server"`
+	Version       string `json:"version"`
+	StartedAt     string `json:"started_at"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	ConfigPath    string `json:"config_path,omitempty"`
+	// ConfigReady is set when a valid test command is registered, so
+	// run_tests can start; ConfigError says why it cannot otherwise.
+	ConfigReady bool   `json:"config_ready"`
+	ConfigError string `json:"config_error,omitempty"`
+}
+
+func registerHealthTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolHealth,
+		Description: "Cheap liveness probe: report the server name, version and uptime, and whether a valid test command is registered. Runs nothing.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args healthArgs) (*mcp.CallToolResult, healthResult, error) {
+		result := healthResult{
+			Server:        serverName,
+			Version:       serverVersion,
+			StartedAt:     formatTime(metrics.startedAt),
+			UptimeSeconds: int64(time.Since(metrics.startedAt).Seconds()),
+		}
+		_, path, _, err := loadConfig("")
+		result.ConfigPath = path
+		if err != nil {
+			result.ConfigError = err.Error()
+		} else {
+			result.ConfigReady = true
+		}
+
+		summary := fmt.Sprintf("%s %s up for %ds; config %s is ready.", serverName, serverVersion, result.UptimeSeconds, result.ConfigPath)
+		if !result.ConfigReady {
+			summary = fmt.Sprintf("%s %s up for %ds; config not ready: %s", serverName, serverVersion, result.UptimeSeconds, result.ConfigError)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, result, nil
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultHermeticEnv asks common toolchains to work offline. It is guidance
+// for well-behaved runners, not a sandbox: nothing stops a command from
+// opening a connection.
+var defaultHermeticEnv = []string{
+	"GOPROXY=off",
+	"npm_config_offline=true",
+	"YARN_ENABLE_OFFLINE_MODE=1",
+	"PIP_NO_INDEX=1",
+	"UV_OFFLINE=1",
+	"CARGO_NET_OFFLINE=true",
+}
+
+// networkTools download something whenever they run; networkSubcommands
+// lists the subcommands that do for other tools.
+var (
+	networkTools       = []string{"curl", "wget", "npx", "bunx"}
+	networkSubcommands = map[string][]string{
+		"npm":    {"install", "i", "ci", "exec"},
+		"pnpm":   {"install", "i", "add", "dlx"},
+		"yarn":   {"install", "add", "dlx"},
+		"pip":    {"install", "download"},
+		"pip3":   {"install", "download"},
+		"go":     {"get", "install"},
+		"cargo":  {"fetch", "install"},
+		"git":    {"clone", "fetch", "pull"},
+		"docker": {"pull"},
+	}
+)
+
+func validateHermeticEnv(hermetic bool, env []string) ([]string, error) {
+	if len(env) > 0 && !hermetic {
+		return nil, errors.New("hermetic_env requires hermetic")
+	}
+	clean, err := validateEnv(env)
+	if err != nil {
+		return nil, fmt.Errorf("hermetic_env: %w", err)
+	}
+	return clean, nil
+}
+
+// hermeticEnv is the environment a hermetic config adds under its own env:
+// hermetic_env when registered, the defaults otherwise.
+func (cfg storedConfig) hermeticEnv() []string {
+	if !cfg.Hermetic {
+		return nil
+	}
+	if len(cfg.HermeticEnv) > 0 {
+		return cfg.HermeticEnv
+	}
+	return defaultHermeticEnv
+}
+
+// networkHint returns the part of cmdline that suggests it needs network
+// access, or "" if none does. Shell scripts passed as one argument are split
+// on whitespace, so this is a heuristic.
+func networkHint(cmdline []string) string {
+	fields := strings.Fields(strings.Join(cmdline, " "))
+	for i, field := range fields {
+		name := strings.TrimSuffix(filepath.Base(field), ".exe")
+		for _, tool := range networkTools {
+			if name == tool {
+				return name
+			}
+		}
+		if i+1 == len(fields) {
+			continue
+		}
+		for _, sub := range networkSubcommands[name] {
+			if fields[i+1] == sub {
+				return name + " " + sub
+			}
+		}
+	}
+	return ""
+}
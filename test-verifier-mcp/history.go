@@ -0,0 +1,167 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolGetRun = "get_run"
+
+	historyURI         = "test-verifier://history"
+	historyURITemplate = historyURI + "{?limit,since,since_last_success,label*}"
+)
+
+type getRunArgs struct {
+	RunID string `json:"run_id" jsonschema:"The run_id returned by run_tests (its trace_id when one was passed)"`
+}
+
+type getRunResult struct {
+	RunID string `json:"run_id"`
+	// Run is the run's summary, while it is among the most recent runs.
+	Run *runSummary `json:"run,omitempty"`
+	// StdoutURI and StderrURI are set while the run's output is in the run
+	// store, i.e. for runs made with output_as_links or whose output was
+	// spilled to disk.
+	StdoutURI string `json:"stdout_uri,omitempty"`
+	StderrURI string `json:"stderr_uri,omitempty"`
+}
+
+func registerGetRunTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolGetRun,
+		Description: fmt.Sprintf("Look up an earlier run by its run_id: its summary (outcome, exit code, duration, labels, git state), kept for the %d most recent runs, and links to its stored output if it was kept. Unknown or expired run IDs are an error.", maxRunHistory),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args getRunArgs) (*mcp.CallToolResult, getRunResult, error) {
+		id := strings.TrimSpace(args.RunID)
+		if id == "" {
+			return nil, getRunResult{}, fmt.Errorf("run_id is required")
+		}
+		if _, err := resolveTraceID(id); err != nil {
+			return nil, getRunResult{}, fmt.Errorf("invalid run_id: %w", err)
+		}
+
+		result := getRunResult{RunID: id}
+		if run, ok := findRun(id); ok {
+			result.Run = &run
+		}
+		var content []mcp.Content
+		if hasRunOutput(id) {
+			result.StdoutURI, result.StderrURI = runOutputURI(id, "stdout"), runOutputURI(id, "stderr")
+			for _, stream := range []string{"stdout", "stderr"} {
+				content = append(content, &mcp.ResourceLink{
+					URI:      runOutputURI(id, stream),
+					Name:     stream,
+					Title:    fmt.Sprintf("%s of run %s", stream, id),
+					MIMEType: "text/plain",
+				})
+			}
+		}
+		if result.Run == nil && content == nil {
+			return nil, getRunResult{}, fmt.Errorf("run %q not found: only the %d most recent runs are kept, and none has that run_id", id, maxRunHistory)
+		}
+
+		summary := fmt.Sprintf("Run %s: output kept, summary no longer in the history.", id)
+		if run := result.Run; run != nil {
+			outcome := "failed"
+			if run.Success {
+				outcome = "passed"
+			}
+			summary = fmt.Sprintf("Run %s started at %s %s with exit code %d in %dms.", id, run.StartedAt, outcome, run.ExitCode, run.DurationMs)
+			if content != nil {
+				summary += " Its output is linked below."
+			}
+		}
+		content = append([]mcp.Content{&mcp.TextContent{Text: summary}}, content...)
+		return &mcp.CallToolResult{Content: content}, result, nil
+	})
+}
+
+// historyPage is the content of the history resource.
+type historyPage struct {
+	// Runs are the matching runs, oldest first, limited to the most recent
+	// limit of them; Matched counts all matching runs.
+	Runs    []runSummary `json:"runs"`
+	Matched int          `json:"matched"`
+}
+
+// parseHistoryQuery reads the history filter and limit from the query of a
+// history resource URI: limit, since, since_last_success and any number of
+// label=key:value.
+func parseHistoryQuery(uri string) (runHistoryFilter, int, error) {
+	var filter runHistoryFilter
+	u, err := url.Parse(uri)
+	if err != nil {
+		return filter, 0, err
+	}
+	query := u.Query()
+	limit := maxRunHistory
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return filter, 0, fmt.Errorf("limit must be a positive number, got %q", raw)
+		}
+	}
+	if filter.Since, err = parseSince(query.Get("since")); err != nil {
+		return filter, 0, err
+	}
+	if raw := query.Get("since_last_success"); raw != "" {
+		if filter.SinceLastSuccess, err = strconv.ParseBool(raw); err != nil {
+			return filter, 0, fmt.Errorf("since_last_success must be true or false, got %q", raw)
+		}
+	}
+	labels := map[string]string{}
+	for _, label := range query["label"] {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok {
+			return filter, 0, fmt.Errorf("label must be key:value, got %q", label)
+		}
+		labels[key] = value
+	}
+	if filter.Labels, err = validateLabels(labels); err != nil {
+		return filter, 0, err
+	}
+	return filter, limit, nil
+}
+
+func registerHistoryResource(server *mcp.Server) {
+	handler := func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		filter, limit, err := parseHistoryQuery(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history query: %w", err)
+		}
+		runs := filterRuns(recentRuns(), filter)
+		page := historyPage{Runs: runs, Matched: len(runs)}
+		if len(runs) > limit {
+			page.Runs = runs[len(runs)-limit:]
+		}
+		data, err := json.Marshal(page)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}}}, nil
+	}
+	description := fmt.Sprintf("Summaries of the %d most recent runs, oldest first, as JSON. Query parameters: limit (keep only the most recent N), since (RFC3339), since_last_success=true, and label=key:value, which may repeat.", maxRunHistory)
+	server.AddResource(&mcp.Resource{
+		Name:        "history",
+		Title:       "Run history",
+		Description: description,
+		MIMEType:    "application/json",
+		URI:         historyURI,
+	}, handler)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "history-query",
+		Title:       "Filtered run history",
+		Description: description,
+		MIMEType:    "application/json",
+		URITemplate: historyURITemplate,
+	}, handler)
+}
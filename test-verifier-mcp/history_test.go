@@ -0,0 +1,91 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestGetRun(t *testing.T) {
+	recordRuns(t,
+		runResult{RunID: "nightly-1", ExitCode: 1, Labels: map[string]string{"suite": "nightly"}},
+		runResult{RunID: "nightly-1", Success: true},
+	)
+	storeRunOutput("linked", storedOutput{stdout: "ok\n"})
+	t.Cleanup(func() { clearRunOutputs(0, false) })
+	session := connect(t, newServer(""))
+
+	var got getRunResult
+	callTool(t, session, toolGetRun, map[string]any{"run_id": "nightly-1"}, &got)
+	if got.Run == nil || !got.Run.Success || got.StdoutURI != "" {
+		t.Errorf("get_run nightly-1 = %+v, want the latest run's summary without output", got)
+	}
+
+	got = getRunResult{}
+	callTool(t, session, toolGetRun, map[string]any{"run_id": "linked"}, &got)
+	if got.Run != nil || got.StdoutURI != runOutputURI("linked", "stdout") {
+		t.Errorf("get_run linked = %+v, want only its output links", got)
+	}
+
+	for _, id := range []string{"missing", "../x", ""} {
+		res, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: toolGetRun, Arguments: map[string]any{"run_id": id}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.IsError {
+			t.Errorf("get_run %q succeeded", id)
+		} else if text := res.Content[0].(*mcp.TextContent).Text; id == "missing" && !strings.Contains(text, "not found") {
+			t.Errorf("get_run %q error = %q, want a not-found error", id, text)
+		}
+	}
+}
+
+func TestHistoryResource(t *testing.T) {
+	recordRuns(t,
+		runResult{RunID: "a", StartedAt: "2026-01-01T10:00:00Z", Labels: map[string]string{"suite": "nightly"}},
+		runResult{RunID: "b", StartedAt: "2026-01-02T10:00:00Z", Success: true},
+		runResult{RunID: "c", StartedAt: "2026-01-03T10:00:00Z", Labels: map[string]string{"suite": "nightly"}},
+		runResult{RunID: "d", StartedAt: "2026-01-04T10:00:00Z"},
+	)
+	session := connect(t, newServer(""))
+
+	tests := []struct {
+		uri         string
+		want        string
+		wantMatched int
+	}{
+		{historyURI, "[a b c d]", 4},
+		{historyURI + "?limit=2", "[c d]", 4},
+		{historyURI + "?label=suite%3Anightly", "[a c]", 2},
+		{historyURI + "?since_last_success=true&limit=1", "[d]", 2},
+		{historyURI + "?since=2026-01-02T00%3A00%3A00Z&label=suite%3Anightly", "[c]", 1},
+		{historyURI + "?label=suite%3Asmoke", "[]", 0},
+	}
+	for _, tt := range tests {
+		res, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: tt.uri})
+		if err != nil {
+			t.Errorf("reading %s: %v", tt.uri, err)
+			continue
+		}
+		var page historyPage
+		if err := json.Unmarshal([]byte(res.Contents[0].Text), &page); err != nil {
+			t.Fatal(err)
+		}
+		if ids := fmt.Sprint(runIDs(page.Runs)); ids != tt.want || page.Matched != tt.wantMatched {
+			t.Errorf("%s: runs %s of %d matched, want %s of %d", tt.uri, ids, page.Matched, tt.want, tt.wantMatched)
+		}
+	}
+
+	for _, uri := range []string{historyURI + "?limit=0", historyURI + "?since=yesterday", historyURI + "?label=suite"} {
+		if _, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri}); err == nil {
+			t.Errorf("reading %s succeeded", uri)
+		}
+	}
+}
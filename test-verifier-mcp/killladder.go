@@ -0,0 +1,109 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxKillLadderSteps  = 4
+	maxKillWaitSeconds  = 300
+	killLadderFinalStep = "KILL"
+)
+
+// killLadderSignals are the signals a kill_ladder rung may send; SIGKILL is
+// always the implicit last rung.
+var killLadderSignals = map[string]bool{"INT": true, "TERM": true, "HUP": true, "QUIT": true}
+
+// killStep is one rung of a kill_ladder: the signal sent to the command's
+// process group and how long to wait for it to exit before the next rung.
+type killStep struct {
+	Signal      string `json:"signal" jsonschema:"INT, TERM, HUP or QUIT"`
+	WaitSeconds int    `json:"wait_seconds" jsonschema:"Seconds to wait for the command to exit before the next rung"`
+}
+
+// validateKillLadder checks every rung and returns the ladder with signal
+// names canonicalized to INT, TERM, HUP or QUIT.
+func validateKillLadder(steps []killStep) ([]killStep, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	if len(steps) > maxKillLadderSteps {
+		return nil, fmt.Errorf("kill_ladder has %d rungs; at most %d are allowed", len(steps), maxKillLadderSteps)
+	}
+	clean := make([]killStep, 0, len(steps))
+	for _, step := range steps {
+		name := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(step.Signal)), "SIG")
+		if !killLadderSignals[name] {
+			return nil, fmt.Errorf("kill_ladder signal %q is not one of INT, TERM, HUP or QUIT (SIGKILL always follows the last rung)", step.Signal)
+		}
+		if step.WaitSeconds < 0 || step.WaitSeconds > maxKillWaitSeconds {
+			return nil, fmt.Errorf("kill_ladder wait_seconds must be between 0 and %d, got %d", maxKillWaitSeconds, step.WaitSeconds)
+		}
+		clean = append(clean, killStep{Signal: name, WaitSeconds: step.WaitSeconds})
+	}
+	return clean, nil
+}
+
+// killLadder stops a timed-out or cancelled command by climbing its rungs,
+// then sending SIGKILL, and remembers the last signal it sent.
+type killLadder struct {
+	steps  []killStep
+	exited chan struct{}
+
+	mu   sync.Mutex
+	sent string
+}
+
+func newKillLadder(steps []killStep) *killLadder {
+	return &killLadder{steps: steps, exited: make(chan struct{})}
+}
+
+// install makes the ladder cmd's Cancel function. It must be called before
+// cmd starts, and done once cmd.Wait has returned.
+func (l *killLadder) install(cmd *exec.Cmd) {
+	total := time.Second
+	for _, step := range l.steps {
+		total += time.Duration(step.WaitSeconds) * time.Second
+	}
+	cmd.Cancel = func() error {
+		go l.climb(cmd.Process.Pid)
+		return nil
+	}
+	// Wait gives up on the pipes only after the whole ladder has run.
+	cmd.WaitDelay = total
+}
+
+func (l *killLadder) climb(pgid int) {
+	for _, step := range l.steps {
+		l.send(pgid, step.Signal)
+		select {
+		case <-l.exited:
+			return
+		case <-time.After(time.Duration(step.WaitSeconds) * time.Second):
+		}
+	}
+	l.send(pgid, killLadderFinalStep)
+}
+
+func (l *killLadder) send(pgid int, name string) {
+	l.mu.Lock()
+	l.sent = name
+	l.mu.Unlock()
+	_ = signalProcessGroup(pgid, name)
+}
+
+// done records that the command has exited and returns the last signal the
+// ladder sent, or "" if it never ran.
+func (l *killLadder) done() string {
+	close(l.exited)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sent
+}
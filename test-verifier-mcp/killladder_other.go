@@ -0,0 +1,21 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errKillLadderUnsupported = errors.New("signalling a process group is only supported on Unix")
+
+func prepareKillLadder(cmd *exec.Cmd) error {
+	return errKillLadderUnsupported
+}
+
+func signalProcessGroup(pgid int, name string) error {
+	return errKillLadderUnsupported
+}
@@ -0,0 +1,33 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+var killLadderSyscalls = map[string]syscall.Signal{
+	"INT":  syscall.SIGINT,
+	"TERM": syscall.SIGTERM,
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+}
+
+// prepareKillLadder starts the command in its own process group so every
+// rung reaches the processes it spawns.
+func prepareKillLadder(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return nil
+}
+
+func signalProcessGroup(pgid int, name string) error {
+	return syscall.Kill(-pgid, killLadderSyscalls[name])
+}
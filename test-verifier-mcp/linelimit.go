@@ -0,0 +1,97 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	maxLineLengthEnvVar  = "TEST_VERIFIER_MAX_LINE_LENGTH"
+	defaultMaxLineLength = 64 << 10
+)
+
+// maxLineLength is the longest line, in bytes, kept from a run's output;
+// the rest of a longer line is dropped (-max-line-length). Zero keeps every
+// line whole.
+var maxLineLength = defaultMaxLineLength
+
+// lineLimiter passes output through to w, cutting every line at limit bytes
+// and noting how much was dropped, so one huge line cannot swamp the
+// captured output. Output that looks binary within its first binarySniffLen
+// bytes is passed through whole in chunks from then on, since its "lines"
+// mean nothing.
+type lineLimiter struct {
+	w        io.Writer
+	limit    int
+	encoding string // the config's output_encoding, for binary detection
+
+	lineLen   int  // bytes of the current line written so far
+	dropped   int  // bytes of the current line dropped so far
+	truncated int  // lines cut short
+	sniffed   int  // bytes inspected for binary output
+	binary    bool // the output looks binary
+}
+
+func (l *lineLimiter) Write(p []byte) (int, error) {
+	if !l.binary && l.sniffed < binarySniffLen {
+		sniff := p[:min(len(p), binarySniffLen-l.sniffed)]
+		l.sniffed += len(sniff)
+		l.binary = looksBinary(sniff, l.encoding)
+	}
+	if l.limit <= 0 || l.binary {
+		return l.w.Write(p)
+	}
+	n := len(p)
+	for len(p) > 0 {
+		line, rest, newline := bytes.Cut(p, []byte{'\n'})
+		keep := min(len(line), max(l.limit-l.lineLen, 0))
+		if l.dropped > 0 {
+			keep = 0
+		}
+		// Cut before a UTF-8 sequence rather than inside it.
+		for keep < len(line) && keep > 0 && !utf8.RuneStart(line[keep]) {
+			keep--
+		}
+		if _, err := l.w.Write(line[:keep]); err != nil {
+			return 0, err
+		}
+		l.lineLen += keep
+		l.dropped += len(line) - keep
+		if !newline {
+			break
+		}
+		if err := l.endLine(); err != nil {
+			return 0, err
+		}
+		if _, err := l.w.Write([]byte{'\n'}); err != nil {
+			return 0, err
+		}
+		p = rest
+	}
+	return n, nil
+}
+
+// flush notes a truncated final line that did not end in a newline. Call it
+// once the command's output is fully copied.
+func (l *lineLimiter) flush() error {
+	if l.binary {
+		return nil
+	}
+	return l.endLine()
+}
+
+func (l *lineLimiter) endLine() error {
+	dropped := l.dropped
+	l.lineLen, l.dropped = 0, 0
+	if dropped == 0 {
+		return nil
+	}
+	l.truncated++
+	_, err := fmt.Fprintf(l.w, " [line truncated: %d bytes dropped]", dropped)
+	return err
+}
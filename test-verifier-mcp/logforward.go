@@ -0,0 +1,132 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxForwardedLogs caps the log notifications sent for one stream of a run.
+const maxForwardedLogs = 200
+
+var (
+	errorLogLine   = regexp.MustCompile(`(?i)\b(error|fatal|panic|fail|failed|failure)\b`)
+	warningLogLine = regexp.MustCompile(`(?i)\b(warn|warning|deprecated|deprecation)\b`)
+)
+
+// logLevel classifies an output line as an error or warning by its wording.
+// Other lines get fallback, or are not forwarded when fallback is empty.
+func logLevel(line string, fallback mcp.LoggingLevel) mcp.LoggingLevel {
+	switch {
+	case errorLogLine.MatchString(line):
+		return "error"
+	case warningLogLine.MatchString(line):
+		return "warning"
+	}
+	return fallback
+}
+
+// logForwarder sends the lines of a run's output stream to the client as MCP
+// log notifications while the command runs. Without all, only stderr lines
+// that read as errors or warnings are sent; with all, every line is, other
+// stdout lines at debug and stderr lines at info level. The session drops
+// messages below the level the client set, and all of them if it set none.
+type logForwarder struct {
+	ctx      context.Context
+	session  *mcp.ServerSession
+	logger   string
+	fallback mcp.LoggingLevel
+	source   *lineLimiter // no lines are forwarded once it sees binary output
+	encoding string
+	filter   func(string) string // the config's output_filters
+
+	partial []byte
+	sent    int
+}
+
+// newLogForwarders returns forwarders for a run's stdout and stderr, or nils
+// when the caller did not ask for logs or has no session. Lines pass through
+// filter, so forwarded logs are redacted like the captured output.
+func newLogForwarders(ctx context.Context, req *mcp.CallToolRequest, args runArgs, runID string, stdout, stderr *lineLimiter, filter func(string) string) (*logForwarder, *logForwarder) {
+	if !args.ForwardLogs || req == nil || req.Session == nil {
+		return nil, nil
+	}
+	forwarder := func(stream string, fallback mcp.LoggingLevel, source *lineLimiter) *logForwarder {
+		return &logForwarder{
+			ctx:      ctx,
+			session:  req.Session,
+			logger:   "run/" + runID + "/" + stream,
+			fallback: fallback,
+			source:   source,
+			encoding: source.encoding,
+			filter:   filter,
+		}
+	}
+	var stdoutLogs *logForwarder
+	stderrFallback := mcp.LoggingLevel("")
+	if args.ForwardAllLogs {
+		stdoutLogs = forwarder("stdout", "debug", stdout)
+		stderrFallback = "info"
+	}
+	return stdoutLogs, forwarder("stderr", stderrFallback, stderr)
+}
+
+func (f *logForwarder) Write(p []byte) (int, error) {
+	n := len(p)
+	if f.source.binary || f.sent > maxForwardedLogs {
+		return n, nil
+	}
+	f.partial = append(f.partial, p...)
+	for {
+		line, rest, ok := bytes.Cut(f.partial, []byte{'\n'})
+		if !ok {
+			break
+		}
+		f.send(line)
+		f.partial = rest
+	}
+	// Copy the unfinished line so partial does not keep the stream's
+	// earlier output alive.
+	f.partial = append([]byte(nil), f.partial...)
+	return n, nil
+}
+
+// flush sends a final line that did not end in a newline.
+func (f *logForwarder) flush() {
+	if f == nil || f.source.binary || len(f.partial) == 0 {
+		return
+	}
+	f.send(f.partial)
+	f.partial = nil
+}
+
+func (f *logForwarder) send(raw []byte) {
+	if f.sent > maxForwardedLogs {
+		return
+	}
+	line := strings.TrimRight(f.filter(stripANSI(decodeOutput(raw, f.encoding, true))), " \t\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	level := logLevel(line, f.fallback)
+	if level == "" {
+		return
+	}
+	f.sent++
+	if f.sent > maxForwardedLogs {
+		level = "notice"
+		line = fmt.Sprintf("more than %d lines; further output of this stream is not forwarded", maxForwardedLogs)
+	}
+	err := f.session.Log(f.ctx, &mcp.LoggingMessageParams{Level: level, Logger: f.logger, Data: line})
+	if err != nil {
+		log.Printf("log notification failed: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// lookPathIn resolves name to an absolute executable path the way the child
+// would see it: relative paths are taken against dir, and bare names are
+// searched on the PATH found in env (falling back to the server's own PATH
+// when env is nil).
+func lookPathIn(name, dir string, env []string) (string, error) {
+	if strings.ContainsRune(name, '/') || (runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`)) {
+		path := name
+		if !filepath.IsAbs(path) {
+			base := dir
+			if base == "" {
+				cwd, err := os.Getwd()
+				if err != nil {
+					return "", err
+				}
+				base = cwd
+			}
+			path = filepath.Join(base, path)
+		}
+		if resolved, ok := findExecutable(path); ok {
+			return resolved, nil
+		}
+		return "", fmt.Errorf("executable %q not found or not executable", path)
+	}
+
+	searchPath, ok := envValue(env, "PATH")
+	if !ok {
+		searchPath = os.Getenv("PATH")
+	}
+	for _, entry := range filepath.SplitList(searchPath) {
+		// Relative PATH entries are ignored, matching exec.LookPath's refusal
+		// to resolve programs from the current directory implicitly.
+		if entry == "" || !filepath.IsAbs(entry) {
+			continue
+		}
+		if resolved, ok := findExecutable(filepath.Join(entry, name)); ok {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("executable %q not found in PATH", name)
+}
+
+func findExecutable(path string) (string, bool) {
+	candidates := []string{path}
+	if runtime.GOOS == "windows" && filepath.Ext(path) == "" {
+		exts := os.Getenv("PATHEXT")
+		if exts == "" {
+			exts = ".com;.exe;.bat;.cmd"
+		}
+		candidates = candidates[:0]
+		for _, ext := range strings.Split(exts, ";") {
+			if ext != "" {
+				candidates = append(candidates, path+strings.ToLower(ext))
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+			continue
+		}
+		return candidate, true
+	}
+	return "", false
+}
+
+// envValue returns the last value of key in a KEY=VALUE list. Keys compare
+// case-insensitively on Windows.
+func envValue(env []string, key string) (string, bool) {
+	if env == nil {
+		return "", false
+	}
+	value, found := "", false
+	for _, entry := range env {
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if k == key || (runtime.GOOS == "windows" && strings.EqualFold(k, key)) {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
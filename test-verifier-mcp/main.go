@@ -4,16 +4,21 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -21,231 +26,1554 @@ import (
 
 const (
 	toolRun               = "run_tests"
+	toolReload            = "reload_config"
 	defaultTimeoutSeconds = 600
 	configEnvVar          = "TEST_VERIFIER_CONFIG"
+	stdinConfigPath       = "-"
+	echoOutputEnvVar      = "TEST_VERIFIER_ECHO_OUTPUT"
+	maxLabels             = 32
+	maxLabelKeyLen        = 64
+	maxLabelValueLen      = 256
+	minNice               = -20
+	maxNice               = 19
 )
 
+// A config that looks cut off mid-write is read again configReadRetries
+// times, configReadRetryDelay apart, before its parse error is reported.
+const (
+	configReadRetries    = 2
+	configReadRetryDelay = 50 * time.Millisecond
+)
+
+// dumpGracePeriod is how long a dump_on_timeout run has to write its goroutine
+// dump after SIGQUIT before the process group is killed.
+const dumpGracePeriod = 2 * time.Second
+
+// stdinConfig holds the config read once at startup in -config-stdin mode.
+// When set, the config file is never consulted.
+var stdinConfig *storedConfig
+
+// echoOutput mirrors child output to the server's stderr for debugging. Never
+// stdout, which carries the MCP stdio transport.
+var echoOutput bool
+
+// cache holds the last parsed config file, keyed by path, mtime and size.
+var cache struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	cfg     storedConfig
+	valid   bool
+}
+
 type storedConfig struct {
 	Command    []string `json:"command"`
 	WorkingDir string   `json:"working_dir,omitempty"`
 	Env        []string `json:"env,omitempty"`
+	Nice       int      `json:"nice,omitempty"`
 	UpdatedAt  string   `json:"updated_at,omitempty"`
+
+	// ExitCodeMessages explains known exit codes, keyed by the decimal code.
+	// SuccessExitCodes lists the codes that count as success (default 0).
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty"`
+	Container        *containerConfig  `json:"container,omitempty"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty"`
+
+	// OutputEncoding is the encoding the command writes (default UTF-8);
+	// captured output is converted to valid UTF-8 either way. OutputFormat
+	// names the structured format of its stdout, parsed into the result's
+	// Tests; only "tap" is supported.
+	OutputEncoding    string `json:"output_encoding,omitempty"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty"`
+	OutputFormat      string `json:"output_format,omitempty"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty"`
+	// filters holds OutputFilters compiled by validateConfig.
+	filters []*regexp.Regexp
+
+	// CacheSources are globs, relative to WorkingDir, of the files whose
+	// content decides whether a cached successful result can be reused.
+	CacheSources []string `json:"cache_sources,omitempty"`
+
+	// InheritEnv is how much of the server's environment the command gets:
+	// all (default), none, or allowlist, which keeps only InheritEnvKeys.
+	InheritEnv     string   `json:"inherit_env,omitempty"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty"`
+
+	// FailOnOutputPatterns are regexps that fail a run when they match its
+	// stdout or stderr, even if the command exited zero.
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty"`
+	// failPatterns holds FailOnOutputPatterns compiled by validateConfig.
+	failPatterns []*regexp.Regexp
+
+	// NoTestsPatterns detect runs that executed no tests (default: pytest,
+	// Jest and Vitest messages); FailOnNoTests fails such runs.
+	NoTestsPatterns []string `json:"no_tests_patterns,omitempty"`
+	FailOnNoTests   bool     `json:"fail_on_no_tests,omitempty"`
+	// noTestsPatterns holds NoTestsPatterns compiled by validateConfig.
+	noTestsPatterns []*regexp.Regexp
+
+	// ArgsFromFile names a manifest, relative to WorkingDir unless absolute,
+	// whose lines are appended to Command when a run starts.
+	ArgsFromFile string `json:"args_from_file,omitempty"`
+
+	// ExpandGlobs expands glob arguments relative to WorkingDir before the
+	// command runs, as a shell would; FailUnmatchedGlobs makes a pattern
+	// without matches an error instead of a literal.
+	ExpandGlobs        bool `json:"expand_globs,omitempty"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty"`
+
+	// TemplateArgs replaces ${VAR} in command arguments with values from the
+	// run's merged environment; unset variables are an error unless
+	// AllowUnsetTemplateVars expands them to "".
+	TemplateArgs           bool `json:"template_args,omitempty"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty"`
+
+	// GitHubReport is where run_tests with report_to_github sets a commit
+	// status.
+	GitHubReport *githubReportConfig `json:"github_report,omitempty"`
+
+	// SmokeCommand is a quick check that run_tests with smoke_first runs
+	// before Command, which only runs if it passes.
+	SmokeCommand []string `json:"smoke_command,omitempty"`
+
+	// SummaryCommand reads a finished run's output on stdin and prints a
+	// short summary for the result; run_tests keeps its own summary if it
+	// fails.
+	SummaryCommand []string `json:"summary_command,omitempty"`
+
+	// VerboseArgs and RaceArgs are appended to the command, after the
+	// args_from_file arguments, by run_tests with verbose or race.
+	VerboseArgs []string `json:"verbose_args,omitempty"`
+	RaceArgs    []string `json:"race_args,omitempty"`
+
+	// FailFastFlag is the runner's stop-at-first-failure flag, e.g.
+	// "-failfast", appended like the other toggles by run_tests with
+	// fail_fast.
+	FailFastFlag string `json:"fail_fast_flag,omitempty"`
+
+	// RunAsUser and RunAsGroup, names or numeric IDs, are the identity the
+	// command runs as on Unix, so a verifier running as root or a service
+	// account can run tests unprivileged. They cannot be combined with
+	// Container.
+	RunAsUser  string `json:"run_as_user,omitempty"`
+	RunAsGroup string `json:"run_as_group,omitempty"`
+
+	// AnomalyFactor turns on anomaly detection: a run whose duration or
+	// output size is this many times above or below the median of the
+	// config's recent runs gets Anomalies. Zero turns it off.
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty"`
+
+	// KillLadder is how a timed-out or cancelled run is stopped: each rung's
+	// signal goes to the command's process group in turn, then SIGKILL.
+	// Without it the command is killed at once.
+	KillLadder []killStep `json:"kill_ladder,omitempty"`
+
+	// EnsureDirs are directories, relative to WorkingDir, created before
+	// each run if they do not exist.
+	EnsureDirs []string `json:"ensure_dirs,omitempty"`
+
+	// TrackFileChanges hashes the files under WorkingDir before and after
+	// each run and reports what the run created, modified or deleted.
+	TrackFileChanges bool `json:"track_file_changes,omitempty"`
+
+	// Hermetic adds environment variables that ask common toolchains to
+	// work offline: HermeticEnv when set, defaultHermeticEnv otherwise.
+	// It does not block network access.
+	Hermetic    bool     `json:"hermetic,omitempty"`
+	HermeticEnv []string `json:"hermetic_env,omitempty"`
 }
 
 type runArgs struct {
-	ExtraArgs      []string `json:"extra_args,omitempty" jsonschema:"Additional arguments appended to the registered command"`
-	TimeoutSeconds int      `json:"timeout_seconds,omitempty" jsonschema:"Optional timeout in seconds (default 600)"`
-	Env            []string `json:"env,omitempty" jsonschema:"Extra environment variables for this run (KEY=VALUE)"`
+	ExtraArgs      []string          `json:"extra_args,omitempty" jsonschema:"Additional arguments appended to the registered command"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty" jsonschema:"Optional timeout in seconds (default 600)"`
+	Env            []string          `json:"env,omitempty" jsonschema:"Extra environment variables for this run (KEY=VALUE)"`
+	Nice           int               `json:"nice,omitempty" jsonschema:"Optional CPU priority for this run as a nice level (-20 to 19); overrides the registered value"`
+	Labels         map[string]string `json:"labels,omitempty" jsonschema:"Optional labels to tag this run with, e.g. {\"suite\":\"nightly\"}; echoed back in the result"`
+	ConfigPath     string            `json:"config_path,omitempty" jsonschema:"Optional config file to run instead of the server default (TEST_VERIFIER_CONFIG)"`
+
+	ExtractFailures bool `json:"extract_failures,omitempty" jsonschema:"When the run fails, return the first lines after a known failure marker (or the stderr tail) as failure_excerpt"`
+	DumpOnTimeout   bool `json:"dump_on_timeout,omitempty" jsonschema:"On timeout, send SIGQUIT to the command's process group and wait briefly before killing it, so Go programs dump goroutine stacks into stderr (Unix only)"`
+	NoCache         bool `json:"no_cache,omitempty" jsonschema:"Always run the command, even if a cached successful result matches the config and cache_sources"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty" jsonschema:"Override the registered inherit_env for this run: all (the server's whole environment), none (only configured and per-run env) or allowlist"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty" jsonschema:"Server environment variables to pass through in allowlist mode, e.g. [\"PATH\",\"HOME\"]"`
+
+	OutputAsLinks bool `json:"output_as_links,omitempty" jsonschema:"Return stdout and stderr as resource links (test-verifier://runs/{id}/stdout and /stderr) instead of inline text, to keep responses small"`
+
+	Pty       bool `json:"pty,omitempty" jsonschema:"Run the command attached to a pseudo-terminal so it prints colors and progress output as in an interactive shell; its combined output, ANSI codes included, is returned as stdout (Unix only)"`
+	StripANSI bool `json:"strip_ansi,omitempty" jsonschema:"Remove ANSI escape codes before matching failure and no-tests patterns and building failure_excerpt; stdout and stderr stay raw"`
+
+	ReportToGitHub bool   `json:"report_to_github,omitempty" jsonschema:"After the run, set a commit status reflecting pass or fail on the registered github_report repository; a GitHub failure only adds a warning and never changes the result"`
+	GitHubSHA      string `json:"github_sha,omitempty" jsonschema:"Commit to set the status on (default: HEAD of working_dir)"`
+
+	SmokeFirst bool `json:"smoke_first,omitempty" jsonschema:"Run the registered smoke_command first and the full command only if it passes; the timeout applies to each phase"`
+
+	OverallTimeoutSeconds int `json:"overall_timeout_seconds,omitempty" jsonschema:"Optional limit in seconds on the whole call: both smoke_first phases, the command, summary_command and report_to_github. When it runs out everything still running is stopped, and the partial result reports the phase in progress as deadline_phase"`
+
+	ExpectExitCode *int `json:"expect_exit_code,omitempty" jsonschema:"Exit code the run is expected to finish with, e.g. 1 to check that a command fails; expectation_met reports the outcome and decides whether the call is an error"`
+
+	CaptureVars map[string]string `json:"capture_vars,omitempty" jsonschema:"Values to capture from this run's output for later runs, as name to Go regexp, e.g. {\"ARTIFACT\":\"built (\\\\S+)\"}; the first group (or the whole match) of the first match in stdout, then stderr, is kept. Later runs in the same session reference it as ${captured.NAME} in the command, extra_args and env"`
+
+	TraceID string `json:"trace_id,omitempty" jsonschema:"Correlation ID for this run, passed to the command in the TEST_RUN_ID environment variable (see -trace-env) and returned as run_id; generated when empty"`
+
+	Verbose bool `json:"verbose,omitempty" jsonschema:"Append the registered verbose_args to the command for this run"`
+	Race    bool `json:"race,omitempty" jsonschema:"Append the registered race_args to the command for this run, e.g. to turn on the Go race detector"`
+
+	FailFast bool `json:"fail_fast,omitempty" jsonschema:"Append the registered fail_fast_flag, e.g. -failfast or --bail, so the runner stops at the first failing test; the result's command shows the effective command"`
+
+	SpillOutput bool `json:"spill_output,omitempty" jsonschema:"Write stdout and stderr to temporary files as they arrive and return only their first and last 256 KiB inline, to save memory on huge logs; the full output is served by the runs resource. Output past -spill-threshold is spilled anyway. fail_on_output_patterns, no-tests detection, capture_vars, output_format parsing and summary_command then only see the inline start and end, and the result warns about it"`
+
+	ForwardLogs    bool `json:"forward_logs,omitempty" jsonschema:"While the command runs, send its stderr lines that read as errors or warnings to the client as MCP log messages (logger run/{run_id}/stderr); the client must have set a log level"`
+	ForwardAllLogs bool `json:"forward_all_logs,omitempty" jsonschema:"With forward_logs, send every stdout and stderr line, other lines at debug (stdout) or info (stderr) level; this duplicates the captured output"`
 }
 
-type runResult struct {
+type reloadArgs struct {
+	ConfigPath string `json:"config_path,omitempty" jsonschema:"Optional config file to reload instead of the server default (TEST_VERIFIER_CONFIG)"`
+}
+
+type reloadResult struct {
 	ConfigPath string   `json:"config_path"`
 	Command    []string `json:"command"`
 	WorkingDir string   `json:"working_dir,omitempty"`
-	ExitCode   int      `json:"exit_code"`
-	DurationMs int64    `json:"duration_ms"`
-	Stdout     string   `json:"stdout,omitempty"`
-	Stderr     string   `json:"stderr,omitempty"`
-	Success    bool     `json:"success"`
-	TimedOut   bool     `json:"timed_out"`
-	Error      string   `json:"error,omitempty"`
 	UpdatedAt  string   `json:"updated_at,omitempty"`
+	Message    string   `json:"message"`
+}
+
+type runResult struct {
+	ConfigPath   string            `json:"config_path"`
+	ConfigCached bool              `json:"config_cached"`
+	Command      []string          `json:"command"`
+	Executable   string            `json:"executable,omitempty"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	ExitCode     int               `json:"exit_code"`
+	DurationMs   int64             `json:"duration_ms"`
+	OutputBytes  int64             `json:"output_bytes,omitempty"`
+	Stdout       string            `json:"stdout,omitempty"`
+	Stderr       string            `json:"stderr,omitempty"`
+	Success      bool              `json:"success"`
+	TimedOut     bool              `json:"timed_out"`
+	Error        string            `json:"error,omitempty"`
+	Nice         int               `json:"nice,omitempty"`
+	Warnings     []string          `json:"warnings,omitempty"`
+	UpdatedAt    string            `json:"updated_at,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	ExitMeaning  string            `json:"exit_meaning,omitempty"`
+	Container    *containerRun     `json:"container,omitempty"`
+
+	FailureExcerpt string      `json:"failure_excerpt,omitempty"`
+	QueueWaitMs    int64       `json:"queue_wait_ms,omitempty"`
+	SystemInfo     *systemInfo `json:"system_info,omitempty"`
+	StartedAt      string      `json:"started_at,omitempty"`
+	FinishedAt     string      `json:"finished_at,omitempty"`
+
+	ConfigFingerprint string `json:"config_fingerprint,omitempty"`
+	// Cached is set when the result was reused from an earlier successful
+	// run instead of executing the command.
+	Cached bool `json:"cached,omitempty"`
+	// InheritEnv is the effective inheritance mode of the run.
+	InheritEnv string `json:"inherit_env,omitempty"`
+
+	// FailureKind says why an otherwise successful run was failed:
+	// "output_pattern" for a fail_on_output_patterns match, recorded in
+	// MatchedPattern, or "no_tests" under fail_on_no_tests.
+	FailureKind    string `json:"failure_kind,omitempty"`
+	MatchedPattern string `json:"matched_pattern,omitempty"`
+	// NoTestsRan is set when the output says no tests were executed.
+	NoTestsRan bool `json:"no_tests_ran,omitempty"`
+	// Captured holds the capture_vars values this run captured.
+	Captured map[string]string `json:"captured,omitempty"`
+	// Tests is parsed from stdout for configs with an output_format.
+	Tests *testReport `json:"tests,omitempty"`
+	// Summary is what the config's summary_command printed for this run.
+	Summary string `json:"summary,omitempty"`
+
+	// RunID is the run's trace ID, also given to the command in the
+	// -trace-env variable. StdoutURI and StderrURI are set for
+	// output_as_links runs, whose output is served by the runs resource
+	// under RunID instead of Stdout and Stderr.
+	RunID     string `json:"run_id,omitempty"`
+	StdoutURI string `json:"stdout_uri,omitempty"`
+	StderrURI string `json:"stderr_uri,omitempty"`
+
+	// StdoutFile and StderrFile are the temporary files holding the full
+	// output of streams that grew past -spill-threshold, or of a run made
+	// with spill_output. Stdout and Stderr then only hold the start and the
+	// end of the stream, and the runs resource serves it all.
+	StdoutFile string `json:"stdout_file,omitempty"`
+	StderrFile string `json:"stderr_file,omitempty"`
+
+	// SuccessExitCodes echoes the exit codes that counted as success.
+	SuccessExitCodes []int `json:"success_exit_codes,omitempty"`
+
+	// ExpectedExitCode and ExpectationMet are set for runs made with
+	// expect_exit_code.
+	ExpectedExitCode *int  `json:"expected_exit_code,omitempty"`
+	ExpectationMet   *bool `json:"expectation_met,omitempty"`
+
+	// Smoke summarizes the passed smoke phase of a smoke_first run.
+	// GatingPhase is "smoke" when a failed smoke phase stopped the run, whose
+	// result is then the smoke run's.
+	Smoke       *smokeResult `json:"smoke,omitempty"`
+	GatingPhase string       `json:"gating_phase,omitempty"`
+
+	// OverallTimedOut is set when the call's overall_timeout_seconds ran
+	// out, and DeadlinePhase names the phase in progress: "smoke",
+	// "command", "summary_command" or "report_to_github".
+	OverallTimedOut bool   `json:"overall_timed_out,omitempty"`
+	DeadlinePhase   string `json:"deadline_phase,omitempty"`
+
+	// Git is the commit and dirty state of the working directory when the
+	// run started, if it is in a git repository.
+	Git *gitState `json:"git,omitempty"`
+
+	// Anomalies lists the metrics that deviate from the config's recent
+	// runs, for configs with an anomaly_factor.
+	Anomalies []anomaly `json:"anomalies,omitempty"`
+
+	// CreatedDirs lists the ensure_dirs entries this run had to create.
+	CreatedDirs []string `json:"created_dirs,omitempty"`
+
+	// FileChanges lists the files the run changed, for configs with
+	// track_file_changes.
+	FileChanges *fileChanges `json:"file_changes,omitempty"`
+
+	// TruncatedLines counts output lines cut at -max-line-length.
+	TruncatedLines int `json:"truncated_lines,omitempty"`
+
+	// Binary is set when stdout or stderr looked binary. Such a stream's
+	// bytes are returned in StdoutBase64 or StderrBase64, and Stdout or
+	// Stderr only says so.
+	Binary       bool   `json:"binary,omitempty"`
+	StdoutBase64 string `json:"stdout_base64,omitempty"`
+	StderrBase64 string `json:"stderr_base64,omitempty"`
+
+	// PolicyViolation is set when the command was refused because its
+	// executable is not on the TEST_VERIFIER_ALLOWED_COMMANDS allowlist.
+	PolicyViolation bool `json:"policy_violation,omitempty"`
+
+	// KilledBy is the last kill_ladder signal sent to a stopped run, e.g.
+	// "TERM", or "KILL" when the command outlived every rung.
+	KilledBy string `json:"killed_by,omitempty"`
+
+	// GitHubStatus reports the commit status set by report_to_github.
+	GitHubStatus *githubStatus `json:"github_status,omitempty"`
+
+	// Pty is set when the command ran on a pseudo-terminal, so Stdout holds
+	// its combined terminal output and Stderr is empty.
+	Pty bool `json:"pty,omitempty"`
 }
 
 func main() {
+	flag.BoolVar(&echoOutput, "echo-output", envBool(echoOutputEnvVar), "Also copy child stdout/stderr to this server's stderr while capturing (also enabled by TEST_VERIFIER_ECHO_OUTPUT=1)")
+	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", durationFromEnv(heartbeatEnvVar, defaultHeartbeatInterval), "Interval between keep-alive progress notifications during a run; 0 disables them (also TEST_VERIFIER_HEARTBEAT_INTERVAL)")
+	flag.DurationVar(&runQueueTimeout, "run-queue-timeout", durationFromEnv(queueTimeoutEnvVar, 0), "How long a run waits for a free slot when -max-concurrent-runs is reached; 0 rejects it immediately (also TEST_VERIFIER_RUN_QUEUE_TIMEOUT)")
+	flag.Float64Var(&softTimeoutFraction, "soft-timeout-fraction", floatFromEnv(softTimeoutEnvVar, defaultSoftTimeoutFraction), "Fraction of a run's timeout after which a warning progress notification is sent; 0 disables it (also TEST_VERIFIER_SOFT_TIMEOUT_FRACTION)")
+	maxRuns := flag.Int("max-concurrent-runs", intFromEnv(maxRunsEnvVar, 0), "Maximum number of test commands running at once across all calls; 0 means unlimited (also TEST_VERIFIER_MAX_CONCURRENT_RUNS)")
+	configStdin := flag.Bool("config-stdin", strings.TrimSpace(os.Getenv(configEnvVar)) == stdinConfigPath, "Read the JSON config once from stdin at startup instead of the config file (also enabled by TEST_VERIFIER_CONFIG=-)")
+	flag.IntVar(&maxLineLength, "max-line-length", intFromEnv(maxLineLengthEnvVar, defaultMaxLineLength), "Longest output line, in bytes, kept from a run; the rest of a longer line is dropped and noted. 0 keeps every line whole (also TEST_VERIFIER_MAX_LINE_LENGTH)")
+	flag.BoolVar(&gitStateDisabled, "no-git", envBool(noGitEnvVar), "Do not record the git commit and dirty state of the working directory with each run (also enabled by TEST_VERIFIER_NO_GIT=1)")
+	flag.BoolVar(&resultCacheDisabled, "no-cache", envBool(noCacheEnvVar), "Never reuse cached successful results, even for configs with cache_sources (also enabled by TEST_VERIFIER_NO_CACHE=1)")
+	flag.DurationVar(&resultCacheTTL, "cache-ttl", durationFromEnv(cacheTTLEnvVar, defaultCacheTTL), "How long a cached successful result stays reusable; 0 keeps it until the config or sources change (also TEST_VERIFIER_CACHE_TTL)")
+	flag.DurationVar(&finishedRunGrace, "finished-run-grace", durationFromEnv(finishedRunGraceEnvVar, defaultFinishedRunGrace), "How long list_runs keeps showing a run after it finished (also TEST_VERIFIER_FINISHED_RUN_GRACE)")
+	flag.IntVar(&spillThreshold, "spill-threshold", intFromEnv(spillThresholdEnvVar, defaultSpillThreshold), "Output size, in bytes, past which a run's stream is written to a temporary file and only its start and end are kept in memory; 0 only spills runs made with spill_output (also TEST_VERIFIER_SPILL_THRESHOLD)")
+	flag.StringVar(&traceEnvVar, "trace-env", stringFromEnv(traceEnvEnvVar, defaultTraceEnvVar), "Environment variable that passes each run's trace ID to the command; empty disables it (also TEST_VERIFIER_TRACE_ENV)")
+	flag.Parse()
+	if strings.ContainsAny(traceEnvVar, "= \t") {
+		log.Fatalf("invalid -trace-env %q: must be an environment variable name", traceEnvVar)
+	}
+	setMaxConcurrentRuns(*maxRuns)
+	allowedCommands = allowedCommandsFromEnv()
+	limit, err := registrationLimitFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	registrationLimit = limit
+
+	instructions := "Run tests with run_tests. The test command is loaded from the shared config file (set by the test-registrar MCP, or by register_and_run here). Use the TEST_VERIFIER_CONFIG env var to point both servers at the same config path."
+	var transport mcp.Transport = &mcp.StdioTransport{}
+	if *configStdin {
+		cfg, rest, err := readStdinConfig(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		stdinConfig = &cfg
+		transport = &mcp.IOTransport{
+			Reader: readCloser{Reader: rest, Closer: os.Stdin},
+			Writer: os.Stdout,
+		}
+		instructions = "Run tests with run_tests. The test command was provided on stdin at startup and is fixed for the lifetime of this server; registrations made through the test-registrar MCP are not picked up."
+		log.Println("config read from stdin; test-registrar updates will be ignored until restart")
+	}
+
+	server := newServer(instructions)
+	if err := server.Run(context.Background(), transport); err != nil {
+		log.Printf("server failed: %v", err)
+	}
+	removeSpillDir()
+}
+
+// newServer builds the verifier with all of its tools, ready to run on any
+// transport.
+func newServer(instructions string) *mcp.Server {
 	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "test-verifier",
+		Name:    serverName,
 		Title:   "Test Verifier MCP Server",
-		Version: "0.1.0",
+		Version: serverVersion,
 	}, &mcp.ServerOptions{
-		Instructions: "Run tests with run_tests. The test command is loaded from the shared config file (set by the test-registrar MCP). Use the TEST_VERIFIER_CONFIG env var to point both servers at the same config path.",
+		Instructions: instructions,
 	})
 
 	registerRunTool(server)
+	registerReloadTool(server)
+	registerRegisterAndRunTool(server)
+	registerShowRunEnvTool(server)
+	registerRunCommandTool(server)
+	registerMetricsTool(server)
+	registerRunOutputResource(server)
+	registerClearHistoryTool(server)
+	registerExportBundleTool(server)
+	registerGetRunTool(server)
+	registerHistoryResource(server)
+	registerEstimateDurationTool(server)
+	registerListRunsTool(server)
+	registerHealthTool(server)
+	return server
+}
 
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Printf("server failed: %v", err)
-	}
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 func registerRunTool(server *mcp.Server) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        toolRun,
 		Description: "Run the registered test command and return stdout, stderr, and exit status.",
-	}, func(ctx context.Context, req *mcp.CallToolRequest, args runArgs) (*mcp.CallToolResult, runResult, error) {
-		cfg, cfgPath, err := loadConfig()
-		if err != nil {
+	}, runTests)
+}
+
+// runTests executes the registered command once and reports the outcome.
+func runTests(ctx context.Context, req *mcp.CallToolRequest, args runArgs) (*mcp.CallToolResult, runResult, error) {
+	cfg, cfgPath, cached, err := loadConfig(args.ConfigPath)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+
+	var warnings []string
+	if stdinConfig != nil {
+		warnings = append(warnings, "config was read from stdin at startup; registrations made since then are ignored")
+	}
+	return runConfig(ctx, req, cfg, cfgPath, cached, args, warnings)
+}
+
+// runConfig executes the command in a validated config once, applying the
+// per-run options in args, and reports the outcome.
+func runConfig(ctx context.Context, req *mcp.CallToolRequest, cfg storedConfig, cfgPath string, cached bool, args runArgs, warnings []string) (*mcp.CallToolResult, runResult, error) {
+	// Both phases of a smoke_first run share one trace ID.
+	runID, err := resolveTraceID(args.TraceID)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	args.TraceID = runID
+	if args.OverallTimeoutSeconds < 0 {
+		return nil, runResult{}, fmt.Errorf("overall_timeout_seconds must not be negative")
+	}
+	ctx, cancelOverall := withOverallTimeout(ctx, args.OverallTimeoutSeconds)
+	defer cancelOverall()
+	if args.SmokeFirst {
+		return runSmokeFirst(ctx, req, cfg, cfgPath, cached, args, warnings)
+	}
+	var identity *runAs
+	if cfg.RunAsUser != "" || cfg.RunAsGroup != "" {
+		if identity, err = resolveRunAs(cfg.RunAsUser, cfg.RunAsGroup); err != nil {
 			return nil, runResult{}, err
 		}
+	}
+	if err := checkPrivileges(identity.dropsRoot()); err != nil {
+		return nil, runResult{}, err
+	}
 
-		extraArgs, err := validateCommand(args.ExtraArgs)
-		if err != nil && len(args.ExtraArgs) > 0 {
-			return nil, runResult{}, fmt.Errorf("extra_args: %w", err)
-		}
+	fingerprint, err := configFingerprint(cfg)
+	if err != nil {
+		return nil, runResult{}, err
+	}
 
-		cmdline := append([]string{}, cfg.Command...)
-		if len(extraArgs) > 0 {
-			cmdline = append(cmdline, extraArgs...)
-		}
+	extraArgs, err := validateCommand(args.ExtraArgs)
+	if err != nil && len(args.ExtraArgs) > 0 {
+		return nil, runResult{}, fmt.Errorf("extra_args: %w", err)
+	}
+	if args.ExpectExitCode != nil && *args.ExpectExitCode < 0 {
+		return nil, runResult{}, fmt.Errorf("expect_exit_code must not be negative")
+	}
+	if args.ReportToGitHub && cfg.GitHubReport == nil {
+		return nil, runResult{}, fmt.Errorf("report_to_github needs a github_report repository in the registered config")
+	}
 
-		runEnv, err := validateEnv(args.Env)
+	cmdline := append([]string{}, cfg.Command...)
+	if cfg.ArgsFromFile != "" {
+		fileArgs, err := readArgsFile(cfg.ArgsFromFile, cfg.WorkingDir)
 		if err != nil {
 			return nil, runResult{}, err
 		}
+		cmdline = append(cmdline, fileArgs...)
+	}
+	toggled, err := toggleArgs(cfg, args)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	cmdline = append(cmdline, toggled...)
+	if len(extraArgs) > 0 {
+		cmdline = append(cmdline, extraArgs...)
+	}
+
+	capturePatterns, err := validateCaptureVars(args.CaptureVars)
+	if err != nil {
+		return nil, runResult{}, err
+	}
 
-		timeoutSeconds := args.TimeoutSeconds
-		if timeoutSeconds <= 0 {
-			timeoutSeconds = defaultTimeoutSeconds
+	runEnv, err := validateEnv(args.Env)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	cfgEnv, err := resolveEnvFiles(cfg.Env)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	// The offline variables go under the config's own env, which wins.
+	if hermeticEnv := cfg.hermeticEnv(); len(hermeticEnv) > 0 {
+		if hermeticEnv, err = resolveEnvFiles(hermeticEnv); err != nil {
+			return nil, runResult{}, err
 		}
+		cfgEnv = mergeEnv(hermeticEnv, cfgEnv)
+	}
+	if runEnv, err = unescapeRunEnv(runEnv); err != nil {
+		return nil, runResult{}, err
+	}
+	// Captured values are substituted after env files are read, so a value
+	// is never taken for a file reference.
+	if cfgEnv, err = expandCapturedEnv(captureSession(req), cfgEnv); err != nil {
+		return nil, runResult{}, err
+	}
+	if runEnv, err = expandCapturedEnv(captureSession(req), runEnv); err != nil {
+		return nil, runResult{}, err
+	}
+
+	labels, err := validateLabels(args.Labels)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+
+	inheritMode, inheritKeys, err := effectiveInheritEnv(cfg, args.InheritEnv, args.InheritEnvKeys)
+	if err != nil {
+		return nil, runResult{}, err
+	}
 
-		start := time.Now()
-		runCtx := ctx
-		var cancel context.CancelFunc
-		if timeoutSeconds > 0 {
-			runCtx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
-			defer cancel()
+	if cfg.TemplateArgs {
+		templateEnv := mergeEnv(inheritedEnv(inheritMode, inheritKeys), cfgEnv, runEnv)
+		if cmdline, err = expandTemplates(cmdline, templateEnv, cfg.AllowUnsetTemplateVars); err != nil {
+			return nil, runResult{}, err
+		}
+	}
+	// After templates, so captured values are never expanded themselves.
+	if cmdline, err = expandCapturedArgs(captureSession(req), cmdline); err != nil {
+		return nil, runResult{}, err
+	}
+	if cfg.ExpandGlobs {
+		if cmdline, err = expandGlobs(cmdline, cfg.WorkingDir, cfg.FailUnmatchedGlobs); err != nil {
+			return nil, runResult{}, err
 		}
+	}
 
-		cmd := exec.CommandContext(runCtx, cmdline[0], cmdline[1:]...)
-		if cfg.WorkingDir != "" {
-			cmd.Dir = cfg.WorkingDir
+	if cfg.Hermetic {
+		if hint := networkHint(cmdline); hint != "" {
+			warnings = append(warnings, fmt.Sprintf("hermetic: the command looks network-dependent (%s); hermetic only sets offline environment variables and does not block network access", hint))
 		}
+	}
+
+	// The allowlist is checked again here so a config edited by hand cannot
+	// bypass it.
+	if err := checkCommandAllowed(cmdline); err != nil {
+		result := runResult{
+			ConfigPath:   cfgPath,
+			ConfigCached: cached,
+			Command:      cmdline,
+			WorkingDir:   cfg.WorkingDir,
+			ExitCode:     -1,
+			Success:      false,
+			Error:        err.Error(),
+			Warnings:     warnings,
+			UpdatedAt:    cfg.UpdatedAt,
+			Labels:       labels,
+			RunID:        runID,
 
-		if len(cfg.Env) > 0 || len(runEnv) > 0 {
-			cmd.Env = append(os.Environ(), cfg.Env...)
-			cmd.Env = append(cmd.Env, runEnv...)
+			ConfigFingerprint: fingerprint,
+			InheritEnv:        inheritMode,
+			PolicyViolation:   true,
 		}
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Test run refused: %v", err)}}}, result, nil
+	}
 
-		var stdout bytes.Buffer
-		var stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+	nice := cfg.Nice
+	if args.Nice != 0 {
+		nice = args.Nice
+	}
+	nice = clampNice(nice)
 
-		err = cmd.Start()
-		if err != nil {
-			result := runResult{
-				ConfigPath: cfgPath,
-				Command:    cmdline,
-				WorkingDir: cfg.WorkingDir,
-				ExitCode:   -1,
-				DurationMs: time.Since(start).Milliseconds(),
-				Stdout:     stdout.String(),
-				Stderr:     stderr.String(),
-				Success:    false,
-				Error:      err.Error(),
-				UpdatedAt:  cfg.UpdatedAt,
+	timeoutSeconds := args.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultTimeoutSeconds
+	}
+
+	var resultKey string
+	if len(cfg.CacheSources) > 0 && !resultCacheDisabled && !args.NoCache {
+		sourceHash, hashErr := hashSources(cfg.WorkingDir, cfg.CacheSources)
+		if hashErr != nil {
+			warnings = append(warnings, fmt.Sprintf("result cache skipped: %v", hashErr))
+		} else {
+			inherit := append([]string{inheritMode}, inheritKeys...)
+			// The resolved env, not just the fingerprinted config, goes into
+			// the key: @file and ${captured.NAME} values can change while
+			// the config stays the same.
+			resultKey = cacheKey(fingerprint, sourceHash, cmdline, inherit, mergeEnv(cfgEnv, runEnv))
+			if hit, ok := lookupCachedResult(resultKey); ok {
+				hit.ConfigPath = cfgPath
+				hit.ConfigCached = cached
+				hit.Warnings = warnings
+				hit.Labels = labels
+				hit.QueueWaitMs = 0
+				hit.Cached = true
+				hit.RunID = runID
+				hit.Captured = nil
+				hit.Anomalies = nil
+				if len(capturePatterns) > 0 {
+					captured, unmatched := captureOutput(captureSession(req), capturePatterns, hit.Stdout, hit.Stderr)
+					hit.Captured = captured
+					hit.Warnings = appendCaptureWarning(hit.Warnings, unmatched)
+				}
+				recordRun(hit)
+				summary := fmt.Sprintf("Reused cached result from %s: sources and config are unchanged (exit code %d).", hit.FinishedAt, hit.ExitCode)
+				if args.ReportToGitHub {
+					reportRun(ctx, cfg, mergeEnv(cfgEnv, runEnv), args.GitHubSHA, &hit, summary)
+				}
+				toolResult := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: checkExpectation(&hit, summary, args.ExpectExitCode)}}}
+				if hit.ExpectationMet != nil {
+					toolResult.IsError = !*hit.ExpectationMet
+				}
+				if args.OutputAsLinks {
+					toolResult.Content = append(toolResult.Content, linkOutput(&hit)...)
+				}
+				return toolResult, hit, nil
 			}
-			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}}, result, nil
 		}
+	}
 
-		err = cmd.Wait()
-		duration := time.Since(start)
+	releaseSlot, queueWait, err := acquireRunSlot(ctx)
+	if errors.Is(err, errAtCapacity) {
+		recordRejected()
 		result := runResult{
-			ConfigPath: cfgPath,
-			Command:    cmdline,
-			WorkingDir: cfg.WorkingDir,
-			DurationMs: duration.Milliseconds(),
-			Stdout:     stdout.String(),
-			Stderr:     stderr.String(),
-			Success:    true,
-			UpdatedAt:  cfg.UpdatedAt,
+			ConfigPath:   cfgPath,
+			ConfigCached: cached,
+			Command:      cmdline,
+			WorkingDir:   cfg.WorkingDir,
+			ExitCode:     -1,
+			Success:      false,
+			Error:        err.Error(),
+			Warnings:     warnings,
+			UpdatedAt:    cfg.UpdatedAt,
+			Labels:       labels,
+			QueueWaitMs:  queueWait.Milliseconds(),
+			SystemInfo:   collectSystemInfo(),
+			RunID:        runID,
+
+			ConfigFingerprint: fingerprint,
+			InheritEnv:        inheritMode,
+		}
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Test run not started: %v", err)}}}, result, nil
+	}
+	if seconds, expired := overallTimedOut(ctx); expired {
+		return nil, runResult{}, fmt.Errorf("overall timeout of %d seconds ran out while waiting for a free run slot", seconds)
+	}
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	defer releaseSlot()
+
+	createdDirs, err := createEnsureDirs(cfg.WorkingDir, cfg.EnsureDirs)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	git := collectGitState(ctx, cfg.WorkingDir)
+	var filesBefore *fileSnapshot
+	if cfg.TrackFileChanges {
+		if snap, snapErr := snapshotFiles(cfg.WorkingDir); snapErr != nil {
+			warnings = append(warnings, fmt.Sprintf("track_file_changes skipped: %v", snapErr))
+		} else {
+			filesBefore = &snap
 		}
+	}
+	start := time.Now()
+	runCtx := ctx
+	var cancel context.CancelFunc
+	if timeoutSeconds > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	// The trace variable is left out of the cache key, so it is added only
+	// now, and overrides any value of the same name.
+	runTraceEnv := traceEnv(runID)
+	var cmdEnv []string
+	if inheritMode != inheritAll || len(cfgEnv) > 0 || len(runEnv) > 0 || len(runTraceEnv) > 0 {
+		// A non-nil empty Env keeps exec from falling back to os.Environ.
+		cmdEnv = append([]string{}, mergeEnv(inheritedEnv(inheritMode, inheritKeys), cfgEnv, runEnv, runTraceEnv)...)
+	}
 
+	argv := cmdline
+	var container *containerRun
+	var executable string
+	policyViolation := false
+	if cfg.Container != nil {
+		hostDir, dirErr := containerHostDir(cfg.WorkingDir)
+		if dirErr != nil {
+			return nil, runResult{}, dirErr
+		}
+		container = &containerRun{Image: cfg.Container.Image, Name: containerName()}
+		argv = containerCommand(cfg.Container, container.Name, hostDir, append(append(append([]string{}, cfgEnv...), runEnv...), runTraceEnv...), cmdline)
+		// Under the allowlist, docker comes from the server's own PATH, not
+		// one the config or caller set.
+		dockerEnv := cmdEnv
+		if allowedCommands != nil {
+			dockerEnv = nil
+		}
+		executable, err = lookPathIn("docker", cfg.WorkingDir, dockerEnv)
 		if err != nil {
-			result.Success = false
-			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
-				result.TimedOut = true
-				result.Error = fmt.Sprintf("timed out after %d seconds", timeoutSeconds)
+			err = fmt.Errorf("container runs require docker: %w", err)
+		}
+	} else {
+		executable, err = lookPathIn(cmdline[0], cfg.WorkingDir, cmdEnv)
+		if err == nil {
+			if err = checkExecutableAllowed(executable); err != nil {
+				policyViolation = true
 			}
+		}
+	}
+	if err != nil {
+		result := runResult{
+			ConfigPath:   cfgPath,
+			ConfigCached: cached,
+			Command:      cmdline,
+			WorkingDir:   cfg.WorkingDir,
+			ExitCode:     -1,
+			Success:      false,
+			Error:        err.Error(),
+			Warnings:     warnings,
+			UpdatedAt:    cfg.UpdatedAt,
+			Labels:       labels,
+			Container:    container,
+			QueueWaitMs:  queueWait.Milliseconds(),
+			SystemInfo:   collectSystemInfo(),
+			Git:          git,
+			CreatedDirs:  createdDirs,
+			RunID:        runID,
 
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				result.ExitCode = exitErr.ExitCode()
-			} else {
-				result.ExitCode = -1
-				if result.Error == "" {
-					result.Error = err.Error()
-				}
+			ConfigFingerprint: fingerprint,
+			InheritEnv:        inheritMode,
+			PolicyViolation:   policyViolation,
+		}
+		recordRun(result)
+		text := fmt.Sprintf("Test run failed to start: %v", err)
+		if policyViolation {
+			text = fmt.Sprintf("Test run refused: %v", err)
+		}
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: text}}}, result, nil
+	}
+
+	// Run the resolved binary but keep the registered argv[0].
+	cmd := exec.CommandContext(runCtx, executable, argv[1:]...)
+	cmd.Args[0] = argv[0]
+	if container != nil {
+		cmd.Cancel = func() error {
+			if killErr := killContainer(executable, container.Name); killErr != nil {
+				log.Printf("failed to kill container %s: %v", container.Name, killErr)
+			}
+			return cmd.Process.Kill()
+		}
+	}
+	dumping := false
+	if args.DumpOnTimeout {
+		if container != nil {
+			warnings = append(warnings, "dump_on_timeout ignored: not supported for container runs")
+		} else if dumpErr := prepareDump(cmd); dumpErr != nil {
+			warnings = append(warnings, fmt.Sprintf("dump_on_timeout ignored: %v", dumpErr))
+		} else {
+			cmd.Cancel = func() error { return dumpAndKill(cmd, dumpGracePeriod) }
+			cmd.WaitDelay = dumpGracePeriod + time.Second
+			dumping = true
+		}
+	}
+	var ladder *killLadder
+	if len(cfg.KillLadder) > 0 {
+		if container != nil {
+			warnings = append(warnings, "kill_ladder ignored: not supported for container runs")
+		} else if dumping {
+			warnings = append(warnings, "kill_ladder ignored: dump_on_timeout sends its own signals")
+		} else if ladderErr := prepareKillLadder(cmd); ladderErr != nil {
+			warnings = append(warnings, fmt.Sprintf("kill_ladder ignored: %v", ladderErr))
+		} else {
+			ladder = newKillLadder(cfg.KillLadder)
+			ladder.install(cmd)
+		}
+	}
+	if cfg.WorkingDir != "" {
+		cmd.Dir = cfg.WorkingDir
+	}
+	cmd.Env = cmdEnv
+	identity.apply(cmd)
+
+	usePty := args.Pty
+	if usePty && container != nil {
+		warnings = append(warnings, "pty ignored: not supported for container runs")
+		usePty = false
+	}
+
+	stdout := newSpillBuffer("stdout", args.SpillOutput)
+	stderr := newSpillBuffer("stderr", args.SpillOutput)
+	output := func(buf *spillBuffer) string {
+		return cfg.filterOutput(decodeOutput(buf.Bytes(), cfg.OutputEncoding, cfg.NormalizeNewlines))
+	}
+	stdoutLines := &lineLimiter{w: stdout, limit: maxLineLength, encoding: cfg.OutputEncoding}
+	stderrLines := &lineLimiter{w: stderr, limit: maxLineLength, encoding: cfg.OutputEncoding}
+	if echoOutput {
+		stdoutLines.w = io.MultiWriter(stdout, os.Stderr)
+		stderrLines.w = io.MultiWriter(stderr, os.Stderr)
+	}
+	stdoutLogs, stderrLogs := newLogForwarders(ctx, req, args, runID, stdoutLines, stderrLines, cfg.filterOutput)
+	if stdoutLogs != nil {
+		stdoutLines.w = io.MultiWriter(stdoutLines.w, stdoutLogs)
+	}
+	if stderrLogs != nil {
+		stderrLines.w = io.MultiWriter(stderrLines.w, stderrLogs)
+	}
+	// A pty run's output is copied from the terminal once it has started.
+	if !usePty {
+		cmd.Stdout = stdoutLines
+		cmd.Stderr = stderrLines
+	}
+
+	if nice != 0 {
+		if prioErr := preparePriority(cmd, nice); prioErr != nil {
+			warnings = append(warnings, fmt.Sprintf("nice %d ignored: %v", nice, prioErr))
+			nice = 0
+		}
+	}
+
+	var terminal *os.File
+	var terminalDone <-chan struct{}
+	if usePty {
+		terminal, err = startPty(cmd)
+		if err == nil {
+			terminalDone = copyPty(stdoutLines, terminal)
+		}
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
+		result := runResult{
+			ConfigPath:   cfgPath,
+			ConfigCached: cached,
+			Command:      cmdline,
+			Executable:   executable,
+			WorkingDir:   cfg.WorkingDir,
+			ExitCode:     -1,
+			DurationMs:   time.Since(start).Milliseconds(),
+			StartedAt:    formatTime(start),
+			FinishedAt:   formatTime(time.Now()),
+			Stdout:       output(stdout),
+			Stderr:       output(stderr),
+			Success:      false,
+			Error:        err.Error(),
+			Warnings:     warnings,
+			UpdatedAt:    cfg.UpdatedAt,
+			Labels:       labels,
+			Container:    container,
+			QueueWaitMs:  queueWait.Milliseconds(),
+			SystemInfo:   collectSystemInfo(),
+			Git:          git,
+			CreatedDirs:  createdDirs,
+			RunID:        runID,
+
+			ConfigFingerprint: fingerprint,
+			InheritEnv:        inheritMode,
+		}
+		recordRun(result)
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}}, result, nil
+	}
+	finishTracking := trackRun(runID, cfgPath, cmdline, labels, cmd.Process.Pid, start)
+
+	if nice != 0 {
+		if prioErr := applyPriority(cmd, nice); prioErr != nil {
+			warnings = append(warnings, fmt.Sprintf("nice %d ignored: %v", nice, prioErr))
+			nice = 0
+		}
+	}
+
+	stopHeartbeat := startHeartbeat(ctx, req, start)
+	stopSoftTimeout := startSoftTimeoutWarning(ctx, req, start, time.Duration(timeoutSeconds)*time.Second)
+	err = cmd.Wait()
+	var killedBy string
+	if ladder != nil {
+		killedBy = ladder.done()
+	}
+	if terminal != nil {
+		finishPty(terminal, terminalDone)
+	}
+	_ = stdoutLines.flush()
+	_ = stderrLines.flush()
+	stdoutLogs.flush()
+	stderrLogs.flush()
+	stdout.close()
+	stderr.close()
+	stopSoftTimeout()
+	stopHeartbeat()
+	finished := time.Now()
+	duration := finished.Sub(start)
+	result := runResult{
+		ConfigPath:   cfgPath,
+		ConfigCached: cached,
+		Command:      cmdline,
+		Executable:   executable,
+		WorkingDir:   cfg.WorkingDir,
+		DurationMs:   duration.Milliseconds(),
+		OutputBytes:  stdout.size + stderr.size,
+		StartedAt:    formatTime(start),
+		FinishedAt:   formatTime(finished),
+		Stdout:       output(stdout),
+		Stderr:       output(stderr),
+		Success:      true,
+		Nice:         nice,
+		Warnings:     warnings,
+		UpdatedAt:    cfg.UpdatedAt,
+		Labels:       labels,
+		Container:    container,
+		QueueWaitMs:  queueWait.Milliseconds(),
+		SystemInfo:   collectSystemInfo(),
+		Git:          git,
+		CreatedDirs:  createdDirs,
+		RunID:        runID,
+
+		ConfigFingerprint: fingerprint,
+		InheritEnv:        inheritMode,
+		SuccessExitCodes:  cfg.successExitCodes(),
+		Pty:               usePty,
+		KilledBy:          killedBy,
+		TruncatedLines:    stdoutLines.truncated + stderrLines.truncated,
+	}
+	applyBinaryOutput(&result, stdoutLines, stderrLines, stdout.Bytes(), stderr.Bytes())
+	storeSpilledOutput(&result, cfg, stdout, stderr)
+	if warning := spilledChecksWarning(cfg, len(capturePatterns) > 0, stdout, stderr); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	if filesBefore != nil {
+		if filesAfter, snapErr := snapshotFiles(cfg.WorkingDir); snapErr != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("track_file_changes skipped: %v", snapErr))
+		} else {
+			result.FileChanges = diffSnapshots(cfg.WorkingDir, *filesBefore, filesAfter, start)
+		}
+	}
+
+	if err != nil {
+		result.Success = false
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			result.TimedOut = true
+			result.Error = fmt.Sprintf("timed out after %d seconds", timeoutSeconds)
+			if seconds, expired := overallTimedOut(ctx); expired {
+				result.Error = fmt.Sprintf("overall timeout of %d seconds ran out", seconds)
+				markOverallTimeout(ctx, &result, phaseCommand)
 			}
-		} else if cmd.ProcessState != nil {
-			result.ExitCode = cmd.ProcessState.ExitCode()
-			if result.ExitCode != 0 {
-				result.Success = false
+		}
+
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			// A signal reports exit code -1, which is never a success code,
+			// and a timed-out run fails whatever its code.
+			result.Success = !result.TimedOut && cfg.isSuccessExitCode(result.ExitCode)
+		} else {
+			result.ExitCode = -1
+			if result.Error == "" {
+				result.Error = err.Error()
 			}
 		}
+	} else if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		if !cfg.isSuccessExitCode(result.ExitCode) {
+			result.Success = false
+		}
+	}
+
+	scanStdout, scanStderr := result.Stdout, result.Stderr
+	if args.StripANSI {
+		scanStdout, scanStderr = stripANSI(scanStdout), stripANSI(scanStderr)
+	}
+	if result.Success {
+		if pattern, ok := cfg.matchFailPattern(scanStdout, scanStderr); ok {
+			result.Success = false
+			result.FailureKind = failureKindOutputPattern
+			result.MatchedPattern = pattern
+		}
+	}
+	if len(capturePatterns) > 0 {
+		captured, unmatched := captureOutput(captureSession(req), capturePatterns, scanStdout, scanStderr)
+		result.Captured = captured
+		result.Warnings = appendCaptureWarning(result.Warnings, unmatched)
+	}
+	if cfg.OutputFormat == outputFormatTAP {
+		result.Tests = parseTAP(scanStdout)
+		if result.Tests.Planned == nil && len(result.Tests.Tests) == 0 && result.Tests.BailOut == "" {
+			result.Warnings = append(result.Warnings, "output_format tap: no TAP plan or test lines found in stdout")
+		}
+		if result.Tests.failed() && result.Success {
+			result.Success = false
+			result.FailureKind = failureKindTAP
+		}
+	}
+	if !result.TimedOut && cfg.noTestsRan(scanStdout, scanStderr) {
+		result.NoTestsRan = true
+		result.Warnings = append(result.Warnings, "no tests ran")
+		if cfg.FailOnNoTests && result.Success {
+			result.Success = false
+			result.FailureKind = failureKindNoTests
+		}
+	}
+
+	if args.ExtractFailures && !result.Success {
+		result.FailureExcerpt = failureExcerpt(scanStdout, scanStderr, cfg.FailureMarkers)
+	}
 
-		summary := fmt.Sprintf("Test run finished with exit code %d.", result.ExitCode)
-		if result.TimedOut {
-			summary = fmt.Sprintf("Test run timed out after %d seconds.", timeoutSeconds)
-		} else if !result.Success && result.ExitCode == -1 && result.Error != "" {
-			summary = fmt.Sprintf("Test run failed to start: %s", result.Error)
+	// A run stopped by the overall timeout has no time left for its hooks.
+	if len(cfg.SummaryCommand) > 0 && result.DeadlinePhase == "" {
+		if text, sumErr := runSummaryCommand(ctx, cfg, cmdEnv, result.Stdout, result.Stderr); sumErr != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("summary_command failed, using the default summary: %v", sumErr))
+		} else {
+			result.Summary = text
 		}
+		markOverallTimeout(ctx, &result, phaseSummaryCommand)
+	}
 
-		toolResult := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}
-		if result.ExitCode == -1 && result.Error != "" {
-			toolResult.IsError = true
+	summary := fmt.Sprintf("Test run finished with exit code %d.", result.ExitCode)
+	if result.ExitCode >= 0 && !result.TimedOut {
+		result.ExitMeaning = cfg.ExitCodeMessages[strconv.Itoa(result.ExitCode)]
+	}
+	if result.DeadlinePhase == phaseCommand {
+		summary = fmt.Sprintf("Test run stopped after %dms: the %s.", result.DurationMs, result.Error)
+	} else if result.TimedOut {
+		summary = fmt.Sprintf("Test run timed out after %d seconds.", timeoutSeconds)
+	} else if !result.Success && result.ExitCode == -1 && result.Error != "" {
+		summary = fmt.Sprintf("Test run failed to start: %s", result.Error)
+	} else if result.ExitMeaning != "" {
+		summary = fmt.Sprintf("Test run finished with exit code %d: %s.", result.ExitCode, result.ExitMeaning)
+	}
+	switch result.FailureKind {
+	case failureKindOutputPattern:
+		summary = strings.TrimSuffix(summary, ".") + fmt.Sprintf(", but failed because its output matched %q.", result.MatchedPattern)
+	case failureKindNoTests:
+		summary = strings.TrimSuffix(summary, ".") + ", but failed because no tests ran."
+	case failureKindTAP:
+		summary = strings.TrimSuffix(summary, ".") + fmt.Sprintf(", but failed because its TAP output reported %s.", result.Tests.describe())
+	}
+
+	summary = checkExpectation(&result, summary, args.ExpectExitCode)
+	if result.Summary != "" {
+		summary += "\n" + result.Summary
+	}
+	// Detected before recordRun, so the run is not part of its own baseline.
+	if cfg.AnomalyFactor > 0 {
+		result.Anomalies = detectAnomalies(result, cfg.AnomalyFactor)
+		for _, a := range result.Anomalies {
+			summary += "\nAnomaly: " + a.describe() + "."
 		}
+	}
+	toolResult := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}
+	if result.ExpectationMet != nil {
+		toolResult.IsError = !*result.ExpectationMet
+	} else if result.ExitCode == -1 && result.Error != "" {
+		toolResult.IsError = true
+	}
+	// A spilled run's files can leave the run store before the cached
+	// result expires, so it is not cached.
+	if result.Success && resultKey != "" && result.StdoutFile == "" && result.StderrFile == "" {
+		storeCachedResult(resultKey, result)
+	}
+	if args.ReportToGitHub && result.DeadlinePhase == "" {
+		reportRun(ctx, cfg, mergeEnv(cfgEnv, runEnv), args.GitHubSHA, &result, summary)
+		markOverallTimeout(ctx, &result, phaseReportToGitHub)
+	}
+	finishTracking(result)
+	recordRun(result)
+	if args.OutputAsLinks {
+		toolResult.Content = append(toolResult.Content, linkOutput(&result)...)
+	}
+
+	return toolResult, result, nil
+}
 
-		return toolResult, result, nil
+func registerReloadTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolReload,
+		Description: "Force the verifier to re-read and re-validate the registered test command config, bypassing its cache.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args reloadArgs) (*mcp.CallToolResult, reloadResult, error) {
+		if stdinConfig != nil {
+			return nil, reloadResult{}, fmt.Errorf("config was read from stdin at startup and cannot be reloaded; restart the server to change it")
+		}
+		invalidateConfigCache()
+		cfg, cfgPath, _, err := loadConfig(args.ConfigPath)
+		if err != nil {
+			return nil, reloadResult{}, err
+		}
+		message := "Config reloaded."
+		result := reloadResult{
+			ConfigPath: cfgPath,
+			Command:    cfg.Command,
+			WorkingDir: cfg.WorkingDir,
+			UpdatedAt:  cfg.UpdatedAt,
+			Message:    message,
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
 	})
 }
 
-func loadConfig() (storedConfig, string, error) {
-	path, err := configPath()
+// loadConfig returns the validated config and its path. The parsed file is
+// cached by modification time and size, so repeated runs only re-parse it
+// after it changes; cached reports whether the cache was used. A non-empty
+// override selects a config file other than the server default.
+func loadConfig(override string) (cfg storedConfig, path string, cached bool, err error) {
+	if stdinConfig != nil {
+		if strings.TrimSpace(override) != "" {
+			return storedConfig{}, "", false, fmt.Errorf("config_path cannot be used when the config was read from stdin at startup")
+		}
+		cfg, err := validateConfig(*stdinConfig)
+		return cfg, stdinConfigPath, true, err
+	}
+
+	path, err = configPath(override)
+	if err != nil {
+		return storedConfig{}, "", false, err
+	}
+
+	info, err := os.Stat(path)
 	if err != nil {
-		return storedConfig{}, "", err
+		return storedConfig{}, path, false, readConfigError(path, err)
 	}
 
-	data, err := os.ReadFile(path)
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.valid && cache.path == path && cache.modTime.Equal(info.ModTime()) && cache.size == info.Size() {
+		cfg, err := validateConfig(cache.cfg)
+		return cfg, path, true, err
+	}
+
+	cfg, info, err = readConfigFile(path, info)
 	if err != nil {
-		return storedConfig{}, path, fmt.Errorf("failed to read config: %w", err)
+		return storedConfig{}, path, false, err
+	}
+
+	cfg, err = validateConfig(cfg)
+	if err != nil {
+		cache.valid = false
+		return storedConfig{}, path, false, err
+	}
+	cache.path, cache.modTime, cache.size, cache.cfg, cache.valid = path, info.ModTime(), info.Size(), cfg, true
+	return cfg, path, false, nil
+}
+
+// checkExpectation records in result whether a run that was expected to
+// exit with expected did so, and returns summary with the outcome appended.
+// A run that timed out or could not be waited for never meets it.
+func checkExpectation(result *runResult, summary string, expected *int) string {
+	if expected == nil {
+		return summary
+	}
+	met := !result.TimedOut && result.Error == "" && result.ExitCode == *expected
+	result.ExpectedExitCode, result.ExpectationMet = expected, &met
+	if met {
+		return summary + fmt.Sprintf(" Expected exit code %d: met.", *expected)
+	}
+	return summary + fmt.Sprintf(" Expected exit code %d: not met.", *expected)
+}
+
+// readConfigFile reads and parses the config at path, whose stat is info.
+// A JSON file that ends in the middle of the JSON may have been caught while
+// it was being written, so it is read again up to configReadRetries times;
+// malformed JSON, YAML or TOML fails at once. The returned stat matches the
+// data parsed.
+func readConfigFile(path string, info os.FileInfo) (storedConfig, os.FileInfo, error) {
+	for attempt := 0; ; attempt++ {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return storedConfig{}, nil, readConfigError(path, err)
+		}
+		var cfg storedConfig
+		err = unmarshalConfig(path, data, &cfg)
+		if err == nil {
+			if attempt > 0 {
+				log.Printf("config %s parsed after %d retries; it was probably read while being written", path, attempt)
+			}
+			return cfg, info, nil
+		}
+		if !truncatedJSON(err) || attempt == configReadRetries {
+			return storedConfig{}, nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+		time.Sleep(configReadRetryDelay)
+		if info, err = os.Stat(path); err != nil {
+			return storedConfig{}, nil, readConfigError(path, err)
+		}
+	}
+}
+
+// truncatedJSON reports whether a json.Unmarshal error means the input
+// ended early, as a partly written file does, rather than being malformed.
+func truncatedJSON(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input"
+}
+
+// readConfigError explains a failure to read the config at path. A missing
+// file means nothing has been registered yet, which gets its own hint.
+func readConfigError(path string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no test command registered; use register_test_command (or %s) first. Expected config at %s", toolRegisterAndRun, path)
 	}
+	return fmt.Errorf("failed to read config: %w", err)
+}
 
+// invalidateConfigCache forces the next loadConfig to re-read the file.
+func invalidateConfigCache() {
+	cache.Lock()
+	cache.valid = false
+	cache.Unlock()
+}
+
+// readStdinConfig decodes a single JSON config from r and returns a reader
+// positioned right after it, so the remaining input can still carry the MCP
+// stdio protocol.
+func readStdinConfig(r io.Reader) (storedConfig, io.Reader, error) {
+	dec := json.NewDecoder(r)
 	var cfg storedConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return storedConfig{}, path, fmt.Errorf("failed to parse config: %w", err)
+	if err := dec.Decode(&cfg); err != nil {
+		return storedConfig{}, nil, fmt.Errorf("failed to parse config from stdin: %w", err)
+	}
+	if _, err := validateConfig(cfg); err != nil {
+		return storedConfig{}, nil, err
 	}
+	return cfg, io.MultiReader(dec.Buffered(), r), nil
+}
 
+func validateConfig(cfg storedConfig) (storedConfig, error) {
 	command, err := validateCommand(cfg.Command)
 	if err != nil {
-		return storedConfig{}, path, fmt.Errorf("invalid command in config: %w", err)
+		return storedConfig{}, fmt.Errorf("invalid command in config: %w", err)
 	}
 	cfg.Command = command
 
+	if len(cfg.SmokeCommand) > 0 {
+		smokeCommand, err := validateCommand(cfg.SmokeCommand)
+		if err != nil {
+			return storedConfig{}, fmt.Errorf("invalid smoke_command in config: %w", err)
+		}
+		cfg.SmokeCommand = smokeCommand
+	}
+	if len(cfg.SummaryCommand) > 0 {
+		summaryCommand, err := validateCommand(cfg.SummaryCommand)
+		if err != nil {
+			return storedConfig{}, fmt.Errorf("invalid summary_command in config: %w", err)
+		}
+		cfg.SummaryCommand = summaryCommand
+	}
+	for _, toggle := range []struct {
+		name string
+		args *[]string
+	}{
+		{"verbose_args", &cfg.VerboseArgs},
+		{"race_args", &cfg.RaceArgs},
+	} {
+		if len(*toggle.args) == 0 {
+			continue
+		}
+		clean, err := validateArgs(*toggle.args)
+		if err != nil {
+			return storedConfig{}, fmt.Errorf("invalid %s in config: %w", toggle.name, err)
+		}
+		*toggle.args = clean
+	}
+	cfg.FailFastFlag = strings.TrimSpace(cfg.FailFastFlag)
+
 	env, err := validateEnv(cfg.Env)
 	if err != nil {
-		return storedConfig{}, path, fmt.Errorf("invalid env in config: %w", err)
+		return storedConfig{}, fmt.Errorf("invalid env in config: %w", err)
 	}
 	cfg.Env = env
 
+	exitCodeMessages, err := validateExitCodeMessages(cfg.ExitCodeMessages)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.ExitCodeMessages = exitCodeMessages
+
+	successExitCodes, err := validateSuccessExitCodes(cfg.SuccessExitCodes)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.SuccessExitCodes = successExitCodes
+
+	githubReport, err := validateGitHubReport(cfg.GitHubReport)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.GitHubReport = githubReport
+
+	killLadder, err := validateKillLadder(cfg.KillLadder)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.KillLadder = killLadder
+
+	ensureDirs, err := validateEnsureDirs(cfg.EnsureDirs)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.EnsureDirs = ensureDirs
+
+	hermeticEnv, err := validateHermeticEnv(cfg.Hermetic, cfg.HermeticEnv)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.HermeticEnv = hermeticEnv
+
+	container, err := validateContainer(cfg.Container)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.Container = container
+	cfg.FailureMarkers = validateFailureMarkers(cfg.FailureMarkers)
+	if err := validateAnomalyFactor(cfg.AnomalyFactor); err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg.RunAsUser, cfg.RunAsGroup = strings.TrimSpace(cfg.RunAsUser), strings.TrimSpace(cfg.RunAsGroup)
+	if cfg.RunAsUser != "" || cfg.RunAsGroup != "" {
+		if cfg.Container != nil {
+			return storedConfig{}, errors.New("invalid config: run_as_user and run_as_group cannot be combined with container")
+		}
+		if _, _, err := lookupRunAs(cfg.RunAsUser, cfg.RunAsGroup); err != nil {
+			return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	encoding, err := normalizeEncoding(cfg.OutputEncoding)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.OutputEncoding = encoding
+
+	outputFormat, err := normalizeOutputFormat(cfg.OutputFormat)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.OutputFormat = outputFormat
+
+	filters, err := compileFilters(cfg.OutputFilters)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.filters = filters
+
+	failPatterns, err := compilePatterns("fail_on_output_patterns", cfg.FailOnOutputPatterns)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.failPatterns = failPatterns
+
+	noTestsPatterns, err := compilePatterns("no_tests_patterns", cfg.NoTestsPatterns)
+	if err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.noTestsPatterns = noTestsPatterns
+
+	if err := validateCacheSources(cfg.CacheSources); err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if _, _, err := normalizeInheritEnv(cfg.InheritEnv, cfg.InheritEnvKeys); err != nil {
+		return storedConfig{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	workingDir, err := expandHome(cfg.WorkingDir)
+	if err != nil {
+		return storedConfig{}, err
+	}
+	if cfg.ArgsFromFile, err = expandHome(cfg.ArgsFromFile); err != nil {
+		return storedConfig{}, err
+	}
+	cfg.WorkingDir = workingDir
+
 	if cfg.WorkingDir != "" {
 		info, statErr := os.Stat(cfg.WorkingDir)
 		if statErr != nil {
-			return storedConfig{}, path, fmt.Errorf("working_dir does not exist: %w", statErr)
+			return storedConfig{}, fmt.Errorf("working_dir does not exist: %w", statErr)
 		}
 		if !info.IsDir() {
-			return storedConfig{}, path, fmt.Errorf("working_dir is not a directory: %s", cfg.WorkingDir)
+			return storedConfig{}, fmt.Errorf("working_dir is not a directory: %s", cfg.WorkingDir)
 		}
 	}
 
-	return cfg, path, nil
+	if err := checkAllowedDir(cfg.WorkingDir); err != nil {
+		return storedConfig{}, err
+	}
+	if err := checkAllowedMounts(cfg.Container, cfg.WorkingDir); err != nil {
+		return storedConfig{}, err
+	}
+
+	return cfg, nil
 }
 
-func configPath() (string, error) {
-	path := strings.TrimSpace(os.Getenv(configEnvVar))
+// configPath returns the absolute config path: override when one is given,
+// otherwise TEST_VERIFIER_CONFIG, otherwise the nearest existing
+// .test-verifier/command.json between the current directory and its
+// repository root, otherwise that file in the current directory. An
+// override must stay inside the allowed roots.
+func configPath(override string) (string, error) {
+	path := strings.TrimSpace(override)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(configEnvVar))
+	}
 	if path == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
 			return "", err
 		}
-		path = filepath.Join(cwd, ".test-verifier", "command.json")
+		path = searchConfig(cwd)
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
 	}
 	abs, err := filepath.Abs(path)
 	if err != nil {
 		return "", err
 	}
+	if err := checkConfigPath(abs); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(override) != "" {
+		if err := checkAllowedConfigPath(abs); err != nil {
+			return "", err
+		}
+	}
 	return abs, nil
 }
 
+// searchConfig returns the first .test-verifier/command.json found in dir
+// or its parents up to the repository root, the nearest directory with a
+// .git entry. Outside a repository, or when none exists, it returns the
+// path in dir.
+func searchConfig(dir string) string {
+	fallback := filepath.Join(dir, ".test-verifier", "command.json")
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return fallback
+		}
+		root = parent
+	}
+	for {
+		candidate := filepath.Join(dir, ".test-verifier", "command.json")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if dir == root {
+			return fallback
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func envBool(name string) bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(name)))
+	return err == nil && v
+}
+
+// formatTime renders t as a UTC RFC 3339 timestamp with sub-second precision.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func intFromEnv(name string, fallback int) int {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("ignoring invalid %s=%q, using default %d", name, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+func floatFromEnv(name string, fallback float64) float64 {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f >= 1 {
+		log.Printf("ignoring invalid %s=%q, using default %g", name, v, fallback)
+		return fallback
+	}
+	return f
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("ignoring invalid %s=%q, using default %s", name, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// stringFromEnv returns the trimmed value of name when it is set, even to an
+// empty string, and fallback otherwise.
+func stringFromEnv(name, fallback string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return strings.TrimSpace(v)
+	}
+	return fallback
+}
+
+// expandHome replaces a leading "~" or "~/" in path with the user's home
+// directory. Other tildes, including "~user", are left alone.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot expand ~ in %q: %w", path, err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
 func validateCommand(command []string) ([]string, error) {
 	if len(command) == 0 {
 		return nil, fmt.Errorf("command must contain at least one element")
@@ -261,6 +1589,34 @@ func validateCommand(command []string) ([]string, error) {
 	return clean, nil
 }
 
+// validateArgs cleans an argument list such as verbose_args. It has no
+// executable, so unlike validateCommand the allowlist does not apply.
+func validateArgs(args []string) ([]string, error) {
+	clean := make([]string, 0, len(args))
+	for _, arg := range args {
+		trimmed := strings.TrimSpace(arg)
+		if trimmed == "" {
+			return nil, fmt.Errorf("argument entries cannot be empty")
+		}
+		if strings.ContainsRune(trimmed, 0) {
+			return nil, fmt.Errorf("argument entries cannot contain NUL bytes")
+		}
+		clean = append(clean, trimmed)
+	}
+	return clean, nil
+}
+
+// clampNice limits a nice level to the range accepted by setpriority(2).
+func clampNice(nice int) int {
+	if nice < minNice {
+		return minNice
+	}
+	if nice > maxNice {
+		return maxNice
+	}
+	return nice
+}
+
 func validateEnv(env []string) ([]string, error) {
 	if len(env) == 0 {
 		return nil, nil
@@ -275,7 +1631,93 @@ func validateEnv(env []string) ([]string, error) {
 		if len(parts) != 2 || parts[0] == "" {
 			return nil, fmt.Errorf("env entries must be KEY=VALUE, got %q", entry)
 		}
+		if parts[1] == envFilePrefix {
+			return nil, fmt.Errorf("env entry %q references a file but gives no path; use KEY=@/path/to/file, or KEY=@@ for a literal @", entry)
+		}
 		clean = append(clean, trimmed)
 	}
 	return clean, nil
 }
+
+func validateLabels(labels map[string]string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	if len(labels) > maxLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed, got %d", maxLabels, len(labels))
+	}
+	clean := make(map[string]string, len(labels))
+	for key, value := range labels {
+		trimmed := strings.TrimSpace(key)
+		if trimmed == "" {
+			return nil, fmt.Errorf("label keys cannot be empty")
+		}
+		if len(trimmed) > maxLabelKeyLen {
+			return nil, fmt.Errorf("label key %q exceeds %d characters", trimmed, maxLabelKeyLen)
+		}
+		if len(value) > maxLabelValueLen {
+			return nil, fmt.Errorf("label %q value exceeds %d characters", trimmed, maxLabelValueLen)
+		}
+		clean[trimmed] = value
+	}
+	return clean, nil
+}
+
+// validateExitCodeMessages checks that every key is a decimal exit code in
+// the range 0-255 and drops entries without a message.
+func validateExitCodeMessages(messages map[string]string) (map[string]string, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	clean := make(map[string]string, len(messages))
+	for key, message := range messages {
+		code, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || code < 0 || code > 255 {
+			return nil, fmt.Errorf("exit_code_messages keys must be exit codes between 0 and 255, got %q", key)
+		}
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+		clean[strconv.Itoa(code)] = message
+	}
+	return clean, nil
+}
+
+// validateSuccessExitCodes checks that every code is in the range 0-255 and
+// returns them sorted without duplicates.
+func validateSuccessExitCodes(codes []int) ([]int, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	seen := make(map[int]bool, len(codes))
+	clean := make([]int, 0, len(codes))
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return nil, fmt.Errorf("success_exit_codes must be exit codes between 0 and 255, got %d", code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			clean = append(clean, code)
+		}
+	}
+	sort.Ints(clean)
+	return clean, nil
+}
+
+// successExitCodes returns the exit codes that count as success.
+func (cfg storedConfig) successExitCodes() []int {
+	if len(cfg.SuccessExitCodes) == 0 {
+		return []int{0}
+	}
+	return cfg.SuccessExitCodes
+}
+
+func (cfg storedConfig) isSuccessExitCode(code int) bool {
+	for _, ok := range cfg.successExitCodes() {
+		if code == ok {
+			return true
+		}
+	}
+	return false
+}
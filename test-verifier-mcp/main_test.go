@@ -0,0 +1,225 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// setHome points os.UserHomeDir at home for the rest of the test.
+func setHome(t *testing.T, home string) {
+	t.Helper()
+	switch runtime.GOOS {
+	case "windows":
+		t.Setenv("USERPROFILE", home)
+	case "plan9":
+		t.Setenv("home", home)
+	default:
+		t.Setenv("HOME", home)
+	}
+}
+
+func TestValidateConfigExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, "app"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	setHome(t, home)
+
+	cfg, err := validateConfig(storedConfig{Command: []string{"go", "test"}, WorkingDir: "~/app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "app"); cfg.WorkingDir != want {
+		t.Errorf("working_dir = %q, want %q", cfg.WorkingDir, want)
+	}
+
+	setHome(t, "")
+	_, err = validateConfig(storedConfig{Command: []string{"go", "test"}, WorkingDir: "~/app"})
+	if err == nil || !strings.Contains(err.Error(), `cannot expand ~ in "~/app"`) {
+		t.Errorf("validateConfig error = %v, want one naming the path", err)
+	}
+}
+
+// connect serves server over in-memory transports and returns a new client
+// session for it.
+func connect(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// callTool calls a tool that must succeed and decodes its structured result
+// into out.
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args, out any) {
+	t.Helper()
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("%s failed: %v", name, res.Content)
+	}
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("%s: decoding result: %v", name, err)
+	}
+}
+
+// TestRegisterThenRun registers a command into the shared config file and
+// runs it in a separate call, checking the config contract end to end.
+func TestRegisterThenRun(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "command.json")
+	t.Setenv(configEnvVar, cfgPath)
+	t.Setenv(allowRootEnvVar, "1")
+	t.Setenv(noGitEnvVar, "1")
+	invalidateConfigCache()
+
+	command := []string{"echo", "round-trip"}
+	if runtime.GOOS == "windows" {
+		command = []string{"cmd", "/c", "echo round-trip"}
+	}
+	session := connect(t, newServer(""))
+
+	var registered registerAndRunResult
+	callTool(t, session, toolRegisterAndRun, map[string]any{"register": map[string]any{"command": command}}, &registered)
+	if registered.Register.ConfigPath != cfgPath {
+		t.Errorf("registered config_path = %q, want %q", registered.Register.ConfigPath, cfgPath)
+	}
+	if _, err := os.Stat(cfgPath); err != nil {
+		t.Fatalf("config not written: %v", err)
+	}
+
+	var ran runResult
+	callTool(t, session, toolRun, map[string]any{}, &ran)
+	if !ran.Success || ran.ExitCode != 0 {
+		t.Errorf("run_tests success = %v, exit code %d, want success with 0 (error %q)", ran.Success, ran.ExitCode, ran.Error)
+	}
+	if got := strings.TrimSpace(ran.Stdout); got != "round-trip" {
+		t.Errorf("run_tests stdout = %q, want %q", got, "round-trip")
+	}
+	if ran.ConfigPath != cfgPath {
+		t.Errorf("run_tests config_path = %q, want %q", ran.ConfigPath, cfgPath)
+	}
+	if ran.ConfigFingerprint != registered.Register.Fingerprint {
+		t.Errorf("run_tests config_fingerprint = %q, want the registered %q", ran.ConfigFingerprint, registered.Register.Fingerprint)
+	}
+}
+
+// TestRegistrarConfigContract has the real test-registrar-mcp, built from
+// ../test-registrar-mcp, write a config that this server then loads and
+// runs, so the two modules cannot drift apart on the config format.
+func TestRegistrarConfigContract(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds test-registrar-mcp")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("registers a shell command")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not on PATH")
+	}
+	registrar := filepath.Join(t.TempDir(), "test-registrar-mcp")
+	build := exec.Command(goTool, "build", "-o", registrar, ".")
+	build.Dir = filepath.Join("..", "test-registrar-mcp")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building test-registrar-mcp: %v\n%s", err, out)
+	}
+
+	workDir := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "command.json")
+	t.Setenv(configEnvVar, cfgPath)
+	t.Setenv(allowRootEnvVar, "1")
+	t.Setenv(noGitEnvVar, "1")
+	invalidateConfigCache()
+
+	cmd := exec.Command(registrar)
+	cmd.Env = append(os.Environ(), configEnvVar+"="+cfgPath)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1"}, nil)
+	registrarSession, err := client.Connect(context.Background(), &mcp.CommandTransport{Command: cmd}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registrarSession.Close()
+
+	res, err := registrarSession.CallTool(context.Background(), &mcp.CallToolParams{Name: "register_test_command", Arguments: map[string]any{
+		"command":            []string{"sh", "-c", "echo $GREETING from $PWD; exit 3"},
+		"working_dir":        workDir,
+		"env":                []string{"GREETING=hello"},
+		"success_exit_codes": []int{0, 3},
+		"exit_code_messages": map[string]string{"3": "partial pass"},
+		"output_filters":     []map[string]string{{"pattern": "hello", "replacement": "hi"}},
+		"verbose_args":       []string{"-x"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError {
+		t.Fatalf("register_test_command failed: %v", res.Content)
+	}
+	var registered struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	data, _ := json.Marshal(res.StructuredContent)
+	if err := json.Unmarshal(data, &registered); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, path, _, err := loadConfig("")
+	if err != nil {
+		t.Fatalf("loading the registrar's config: %v", err)
+	}
+	if path != cfgPath {
+		t.Errorf("config path = %q, want %q", path, cfgPath)
+	}
+	resolvedWorkDir, _ := filepath.EvalSymlinks(workDir)
+	if want := []string{"sh", "-c", "echo $GREETING from $PWD; exit 3"}; !reflect.DeepEqual(cfg.Command, want) {
+		t.Errorf("command = %q, want %q", cfg.Command, want)
+	}
+	if cfg.WorkingDir != workDir && cfg.WorkingDir != resolvedWorkDir {
+		t.Errorf("working_dir = %q, want %q", cfg.WorkingDir, workDir)
+	}
+	if !reflect.DeepEqual(cfg.Env, []string{"GREETING=hello"}) || !reflect.DeepEqual(cfg.SuccessExitCodes, []int{0, 3}) ||
+		cfg.ExitCodeMessages["3"] != "partial pass" || len(cfg.OutputFilters) != 1 || !reflect.DeepEqual(cfg.VerboseArgs, []string{"-x"}) {
+		t.Errorf("config = %+v, want the registered env, exit codes, filters and verbose_args", cfg)
+	}
+
+	var ran runResult
+	callTool(t, connect(t, newServer("")), toolRun, map[string]any{}, &ran)
+	if !ran.Success || ran.ExitCode != 3 {
+		t.Errorf("run_tests success = %v, exit code %d, want success with 3 (error %q)", ran.Success, ran.ExitCode, ran.Error)
+	}
+	if got := strings.TrimSpace(ran.Stdout); !strings.HasPrefix(got, "hi from ") || !strings.HasSuffix(got, filepath.Base(workDir)) {
+		t.Errorf("run_tests stdout = %q, want the filtered greeting from the working_dir", got)
+	}
+	if ran.ConfigFingerprint != registered.Fingerprint {
+		t.Errorf("run_tests config_fingerprint = %q, want the registrar's %q", ran.ConfigFingerprint, registered.Fingerprint)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolMetrics = "metrics"
+
+// metrics counts the runs handled since the server started. Counters are
+// kept in memory and reset on restart.
+var metrics struct {
+	startedAt       time.Time
+	runs            atomic.Int64
+	succeeded       atomic.Int64
+	failed          atomic.Int64
+	timedOut        atomic.Int64
+	cacheHits       atomic.Int64
+	rejected        atomic.Int64
+	totalDurationMs atomic.Int64
+}
+
+type metricsArgs struct{}
+
+type metricsResult struct {
+	StartedAt         string `json:"started_at"`
+	Runs              int64  `json:"runs"`
+	Succeeded         int64  `json:"succeeded"`
+	Failed            int64  `json:"failed"`
+	TimedOut          int64  `json:"timed_out"`
+	CacheHits         int64  `json:"cache_hits"`
+	Rejected          int64  `json:"rejected"`
+	AverageDurationMs int64  `json:"average_duration_ms"`
+}
+
+func init() {
+	metrics.startedAt = time.Now()
+}
+
+// recordRun counts a finished run and adds it to the run history. Cached
+// results are counted separately and do not affect the duration average.
+func recordRun(result runResult) {
+	appendRunHistory(result)
+	if result.Cached {
+		metrics.cacheHits.Add(1)
+		return
+	}
+	metrics.runs.Add(1)
+	if result.Success {
+		metrics.succeeded.Add(1)
+	} else {
+		metrics.failed.Add(1)
+	}
+	if result.TimedOut {
+		metrics.timedOut.Add(1)
+	}
+	metrics.totalDurationMs.Add(result.DurationMs)
+}
+
+// recordRejected counts a run turned away because the server was at
+// capacity.
+func recordRejected() {
+	metrics.rejected.Add(1)
+}
+
+func registerMetricsTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolMetrics,
+		Description: "Report counters for the runs this verifier has handled since it started: totals, successes, failures, timeouts, cache hits, runs rejected at capacity, and the average run duration.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args metricsArgs) (*mcp.CallToolResult, metricsResult, error) {
+		result := metricsResult{
+			StartedAt: formatTime(metrics.startedAt),
+			Runs:      metrics.runs.Load(),
+			Succeeded: metrics.succeeded.Load(),
+			Failed:    metrics.failed.Load(),
+			TimedOut:  metrics.timedOut.Load(),
+			CacheHits: metrics.cacheHits.Load(),
+			Rejected:  metrics.rejected.Load(),
+		}
+		if result.Runs > 0 {
+			result.AverageDurationMs = metrics.totalDurationMs.Load() / result.Runs
+		}
+		summary := fmt.Sprintf("%d runs since %s: %d succeeded, %d failed (%d timed out); %d cache hits, %d rejected; average duration %d ms.",
+			result.Runs, result.StartedAt, result.Succeeded, result.Failed, result.TimedOut, result.CacheHits, result.Rejected, result.AverageDurationMs)
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, result, nil
+	})
+}
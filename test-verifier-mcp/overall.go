@@ -0,0 +1,50 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phases reported in runResult.DeadlinePhase besides phaseSmoke.
+const (
+	phaseCommand        = "command"
+	phaseSummaryCommand = "summary_command"
+	phaseReportToGitHub = "report_to_github"
+)
+
+type overallTimeoutKey struct{}
+
+// withOverallTimeout bounds ctx by a call's overall_timeout_seconds. It
+// applies once per call, so both phases of a smoke_first run share the
+// deadline set by the outer runConfig.
+func withOverallTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 || ctx.Value(overallTimeoutKey{}) != nil {
+		return ctx, func() {}
+	}
+	ctx = context.WithValue(ctx, overallTimeoutKey{}, seconds)
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
+
+// overallTimedOut reports whether the overall deadline of ctx has passed,
+// and the overall timeout in seconds.
+func overallTimedOut(ctx context.Context) (int, bool) {
+	seconds, ok := ctx.Value(overallTimeoutKey{}).(int)
+	return seconds, ok && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// markOverallTimeout records on result that the overall deadline ran out
+// during phase, if it has and no earlier phase was recorded.
+func markOverallTimeout(ctx context.Context, result *runResult, phase string) {
+	seconds, expired := overallTimedOut(ctx)
+	if !expired || result.DeadlinePhase != "" {
+		return
+	}
+	result.OverallTimedOut = true
+	result.DeadlinePhase = phase
+	result.Warnings = append(result.Warnings, fmt.Sprintf("overall timeout of %d seconds ran out during %s", seconds, phase))
+}
@@ -0,0 +1,12 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !windows
+
+package main
+
+// checkConfigPath has nothing to check outside Windows: any byte string the
+// kernel accepts is a usable path, and length errors are already clear.
+func checkConfigPath(path string) error {
+	return nil
+}
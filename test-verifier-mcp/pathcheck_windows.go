@@ -0,0 +1,41 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxExtendedPath is the longest path Windows accepts, in UTF-16 code
+	// units, even with the \\?\ extended-length prefix.
+	maxExtendedPath = 32767
+	// maxPathComponent is the longest single file or directory name.
+	maxPathComponent = 255
+)
+
+// checkConfigPath rejects config paths Windows cannot store, with a clear
+// message instead of the cryptic error a later file operation would give.
+// Paths longer than MAX_PATH need no special handling: the os package adds
+// the \\?\ prefix to long absolute paths itself.
+func checkConfigPath(path string) error {
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("config path %q is not valid UTF-8 and cannot be used on Windows", path)
+	}
+	if n := len(utf16.Encode([]rune(path))); n > maxExtendedPath {
+		return fmt.Errorf("config path is too long (%d characters, Windows allows %d)", n, maxExtendedPath)
+	}
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' })
+	for _, part := range parts {
+		if n := len(utf16.Encode([]rune(part))); n > maxPathComponent {
+			return fmt.Errorf("config path component %q is too long (%d characters, Windows allows %d)", part, n, maxPathComponent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckExecutableAllowed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as executables")
+	}
+	writeTool := func(dir string) string {
+		path := filepath.Join(dir, "tool")
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	trusted := writeTool(t.TempDir())
+	planted := writeTool(t.TempDir())
+	t.Setenv("PATH", filepath.Dir(trusted))
+	defer func(saved []string) { allowedCommands = saved }(allowedCommands)
+
+	allowedCommands = nil
+	if err := checkExecutableAllowed(planted); err != nil {
+		t.Errorf("without an allowlist: %v", err)
+	}
+
+	allowedCommands = []string{"tool"}
+	if err := checkExecutableAllowed(trusted); err != nil {
+		t.Errorf("tool on the server's PATH refused: %v", err)
+	}
+	if err := checkExecutableAllowed(planted); err == nil {
+		t.Error("tool from another PATH allowed")
+	}
+
+	allowedCommands = []string{planted}
+	if err := checkExecutableAllowed(planted); err != nil {
+		t.Errorf("allowlisted absolute path refused: %v", err)
+	}
+}
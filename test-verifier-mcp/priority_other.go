@@ -0,0 +1,21 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix && !windows
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errPriorityUnsupported = errors.New("process priority is not supported on this platform")
+
+func preparePriority(cmd *exec.Cmd, nice int) error {
+	return errPriorityUnsupported
+}
+
+func applyPriority(cmd *exec.Cmd, nice int) error {
+	return errPriorityUnsupported
+}
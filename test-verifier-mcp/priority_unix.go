@@ -0,0 +1,30 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// preparePriority starts the child in a process group of its own, so that
+// applyPriority can renice everything it runs.
+func preparePriority(cmd *exec.Cmd, nice int) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return nil
+}
+
+// applyPriority renices the started child's process group. Processes it
+// forked before the call, such as the compiler and test binaries of go test,
+// are in the group and reniced with it; later ones inherit the nice level.
+// Raising priority (negative nice) usually requires privileges, in which
+// case the error is reported as a warning.
+func applyPriority(cmd *exec.Cmd, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, nice)
+}
@@ -0,0 +1,46 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const (
+	idlePriorityClass        = 0x00000040
+	belowNormalPriorityClass = 0x00004000
+	aboveNormalPriorityClass = 0x00008000
+	highPriorityClass        = 0x00000080
+)
+
+// preparePriority maps the nice level onto a Windows priority class, which
+// has to be chosen at process creation time.
+func preparePriority(cmd *exec.Cmd, nice int) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= priorityClass(nice)
+	return nil
+}
+
+// applyPriority is a no-op on Windows; the priority class is set at creation.
+func applyPriority(cmd *exec.Cmd, nice int) error {
+	return nil
+}
+
+func priorityClass(nice int) uint32 {
+	switch {
+	case nice >= 15:
+		return idlePriorityClass
+	case nice > 0:
+		return belowNormalPriorityClass
+	case nice <= -15:
+		return highPriorityClass
+	default:
+		return aboveNormalPriorityClass
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+const allowRootEnvVar = "TEST_VERIFIER_ALLOW_ROOT"
+
+var errPrivileged = errors.New("refusing to run commands as root or an elevated administrator; register a run_as_user or set TEST_VERIFIER_ALLOW_ROOT=1 to allow it")
+
+// checkPrivileges refuses runs while the verifier has root or elevated
+// administrator rights, unless TEST_VERIFIER_ALLOW_ROOT is set or the
+// command drops root through run_as_user.
+func checkPrivileges(dropsRoot bool) error {
+	if envBool(allowRootEnvVar) || dropsRoot {
+		return nil
+	}
+	privileged, err := isPrivileged()
+	if err != nil {
+		log.Printf("could not determine whether the verifier runs elevated: %v", err)
+		return nil
+	}
+	if privileged {
+		return errPrivileged
+	}
+	return nil
+}
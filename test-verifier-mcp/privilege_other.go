@@ -0,0 +1,12 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix && !windows
+
+package main
+
+// isPrivileged always reports false where there is no root or elevated
+// administrator to detect.
+func isPrivileged() (bool, error) {
+	return false, nil
+}
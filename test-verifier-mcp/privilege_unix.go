@@ -0,0 +1,13 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import "os"
+
+// isPrivileged reports whether the verifier runs as root.
+func isPrivileged() (bool, error) {
+	return os.Geteuid() == 0, nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// tokenElevation is the TOKEN_INFORMATION_CLASS value for TokenElevation.
+const tokenElevation = 20
+
+// isPrivileged reports whether the verifier's process token is elevated,
+// i.e. it runs with administrator rights.
+func isPrivileged() (bool, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return false, err
+	}
+	defer token.Close()
+
+	var elevated uint32
+	var n uint32
+	if err := syscall.GetTokenInformation(token, tokenElevation, (*byte)(unsafe.Pointer(&elevated)), uint32(unsafe.Sizeof(elevated)), &n); err != nil {
+		return false, err
+	}
+	return elevated != 0, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	heartbeatEnvVar          = "TEST_VERIFIER_HEARTBEAT_INTERVAL"
+	defaultHeartbeatInterval = 10 * time.Second
+
+	softTimeoutEnvVar          = "TEST_VERIFIER_SOFT_TIMEOUT_FRACTION"
+	defaultSoftTimeoutFraction = 0.9
+)
+
+// heartbeatInterval is how often a running command reports that it is still
+// alive. Zero disables heartbeats.
+var heartbeatInterval = defaultHeartbeatInterval
+
+// softTimeoutFraction is the share of a run's timeout after which a warning
+// is sent that the run will soon be killed. Zero disables the warning.
+var softTimeoutFraction = defaultSoftTimeoutFraction
+
+// progressToken returns the caller's progress token, or nil when the request
+// did not ask for progress notifications.
+func progressToken(req *mcp.CallToolRequest) any {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return nil
+	}
+	return req.Params.GetProgressToken()
+}
+
+// notifyProgress sends a best-effort progress notification for req.
+func notifyProgress(ctx context.Context, req *mcp.CallToolRequest, progress float64, message string) {
+	token := progressToken(req)
+	if token == nil {
+		return
+	}
+	err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Message:       message,
+	})
+	if err != nil {
+		log.Printf("progress notification failed: %v", err)
+	}
+}
+
+// startHeartbeat reports elapsed time every heartbeatInterval until the
+// returned stop function is called. The first heartbeat is only sent after a
+// full interval, so fast runs never emit one. stop waits for the heartbeat
+// goroutine to exit.
+func startHeartbeat(ctx context.Context, req *mcp.CallToolRequest, start time.Time) (stop func()) {
+	if heartbeatInterval <= 0 || progressToken(req) == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				elapsed := time.Since(start).Truncate(time.Second)
+				notifyProgress(ctx, req, elapsed.Seconds(), fmt.Sprintf("Test command still running (%s elapsed).", elapsed))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
+
+// startSoftTimeoutWarning sends one progress notification once
+// softTimeoutFraction of timeout has elapsed, saying how long the run has
+// left before it is killed. stop cancels a warning that has not fired yet.
+func startSoftTimeoutWarning(ctx context.Context, req *mcp.CallToolRequest, start time.Time, timeout time.Duration) (stop func()) {
+	if softTimeoutFraction <= 0 || softTimeoutFraction >= 1 || timeout <= 0 || progressToken(req) == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(time.Duration(float64(timeout) * softTimeoutFraction))
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		case <-timer.C:
+			elapsed := time.Since(start)
+			remaining := (timeout - elapsed).Round(time.Second)
+			notifyProgress(ctx, req, elapsed.Seconds(), fmt.Sprintf("Test command is close to its %s timeout and will be killed in about %s.", timeout, remaining))
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
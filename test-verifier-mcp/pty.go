@@ -0,0 +1,53 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Window size reported to pty runs. A wide terminal keeps runners from
+// wrapping or truncating their progress lines.
+const (
+	ptyRows = 50
+	ptyCols = 200
+)
+
+// ptyDrainTimeout bounds how long output is still read after the command
+// exits, in case a background process it started keeps the terminal open.
+const ptyDrainTimeout = 2 * time.Second
+
+// ansiEscape matches ANSI CSI sequences (colors, cursor movement) and OSC
+// sequences (window titles, hyperlinks).
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+func stripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// copyPty copies the output of a pty run to w until the terminal reports
+// EOF, which Linux signals as EIO once every process has closed it. The
+// returned channel is closed when copying stops.
+func copyPty(w io.Writer, master *os.File) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(w, master)
+	}()
+	return done
+}
+
+// finishPty waits for copyPty to drain the terminal, giving up after
+// ptyDrainTimeout, and closes it.
+func finishPty(master *os.File, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(ptyDrainTimeout):
+	}
+	master.Close()
+	<-done
+}
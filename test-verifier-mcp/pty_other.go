@@ -0,0 +1,16 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func startPty(cmd *exec.Cmd) (*os.File, error) {
+	return nil, errors.New("pty runs are only supported on Unix")
+}
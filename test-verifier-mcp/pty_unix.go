@@ -0,0 +1,30 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// startPty starts cmd with a new pseudo-terminal as its controlling
+// terminal and stdin, stdout and stderr, and returns the terminal's master
+// side, from which the caller reads the command's output.
+func startPty(cmd *exec.Cmd) (*os.File, error) {
+	attrs := cmd.SysProcAttr
+	if attrs == nil {
+		attrs = &syscall.SysProcAttr{}
+	}
+	// Setsid already makes the command a process group leader, and setpgid
+	// fails for a session leader.
+	attrs.Setpgid = false
+	attrs.Setsid = true
+	attrs.Setctty = true
+	return pty.StartWithAttrs(cmd, &pty.Winsize{Rows: ptyRows, Cols: ptyCols}, attrs)
+}
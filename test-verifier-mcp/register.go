@@ -0,0 +1,409 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const toolRegisterAndRun = "register_and_run"
+
+// registerArgs mirrors the test-registrar's register_test_command input so
+// both servers write the same config shape.
+type registerArgs struct {
+	Command    []string `json:"command" jsonschema:"Command and arguments to run the tests, e.g. [\"npm\",\"test\"]"`
+	WorkingDir string   `json:"working_dir,omitempty" jsonschema:"Optional working directory for running the command"`
+	Env        []string `json:"env,omitempty" jsonschema:"Optional environment variables as KEY=VALUE"`
+	Nice       int      `json:"nice,omitempty" jsonschema:"Optional CPU priority for test runs as a nice level (-20 to 19); higher values are less disruptive"`
+
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty" jsonschema:"Optional explanations for known exit codes, keyed by code, e.g. {\"5\":\"no tests were collected\"}"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty" jsonschema:"Exit codes that count as success (default [0]), e.g. [0,1] for a linter that exits 1 on warnings; timeouts and signals still fail"`
+	Container        *containerConfig  `json:"container,omitempty" jsonschema:"Optional Docker container to run the command in instead of the host"`
+	AllowShellTokens bool              `json:"allow_shell_tokens,omitempty" jsonschema:"Register even if the command contains shell operators such as && or |, which are passed to the program literally"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty" jsonschema:"Optional substrings that mark the start of a failure report, used by run_tests extract_failures (default FAIL, Error:, panic:, AssertionError, ✕)"`
+	ConfigPath       string            `json:"config_path,omitempty" jsonschema:"Optional config file to write instead of the server default (TEST_VERIFIER_CONFIG)"`
+
+	OutputEncoding    string `json:"output_encoding,omitempty" jsonschema:"Encoding the command writes its output in: utf-8 (default), windows-1252 or iso-8859-1; output is always returned as valid UTF-8"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty" jsonschema:"Convert CRLF line endings in captured output to LF"`
+	OutputFormat      string `json:"output_format,omitempty" jsonschema:"Structured format the command writes its test results in on stdout: tap (Test Anything Protocol). run_tests then reports each test in tests and fails the run on failed or missing tests"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty" jsonschema:"Optional regexp replacements applied in order to captured stdout and stderr, e.g. to normalize paths or timestamps"`
+	CacheSources  []string     `json:"cache_sources,omitempty" jsonschema:"Optional globs relative to working_dir (** matches any directories), e.g. [\"**/*.go\",\"go.sum\"]; when set, run_tests reuses the last successful result while these files and the config are unchanged"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty" jsonschema:"How much of the verifier's environment the command gets: all (default), none (only env above) or allowlist (only inherit_env_keys)"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty" jsonschema:"Verifier environment variables to pass through in allowlist mode, e.g. [\"PATH\",\"HOME\"]"`
+
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty" jsonschema:"Optional Go regexps that fail a run when they match stdout or stderr, even with exit code 0, e.g. [\"WARNING: DATA RACE\"]"`
+	NoTestsPatterns      []string `json:"no_tests_patterns,omitempty" jsonschema:"Optional Go regexps whose match in the output means no tests ran (default: pytest, Jest and Vitest messages); reported as no_tests_ran"`
+	FailOnNoTests        bool     `json:"fail_on_no_tests,omitempty" jsonschema:"Fail a run whose output says no tests ran, even with exit code 0"`
+	ArgsFromFile         string   `json:"args_from_file,omitempty" jsonschema:"Optional manifest file, relative to working_dir unless absolute, whose lines are appended to the command at run time; blank lines and # comments are skipped"`
+
+	ExpandGlobs        bool `json:"expand_globs,omitempty" jsonschema:"Expand glob arguments such as src/**/*.js relative to working_dir at run time, as a shell would; flags are left alone"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty" jsonschema:"With expand_globs, fail the run when a pattern matches nothing instead of passing it through literally"`
+
+	TemplateArgs           bool `json:"template_args,omitempty" jsonschema:"Replace ${VAR} in command arguments with the run's environment, e.g. [\"go\",\"test\",\"-run\",\"${TEST_PATTERN}\"] with env TEST_PATTERN=... on run_tests; $${ escapes a literal ${"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty" jsonschema:"With template_args, expand unset variables to an empty string instead of failing the run"`
+
+	GitHubReport *githubReportConfig `json:"github_report,omitempty" jsonschema:"Optional GitHub repository on which run_tests with report_to_github sets a commit status"`
+	SmokeCommand []string            `json:"smoke_command,omitempty" jsonschema:"Optional quick command, e.g. [\"go\",\"vet\",\"./...\"], that run_tests with smoke_first runs before the full command"`
+	KillLadder   []killStep          `json:"kill_ladder,omitempty" jsonschema:"Optional signals sent in turn to the process group of a timed-out or cancelled run, e.g. [{\"signal\":\"TERM\",\"wait_seconds\":10}], before SIGKILL (Unix only; default: kill at once)"`
+	EnsureDirs   []string            `json:"ensure_dirs,omitempty" jsonschema:"Optional directories, relative to working_dir, that run_tests creates before running if missing, e.g. [\"test-results\",\"coverage\"]"`
+
+	TrackFileChanges bool `json:"track_file_changes,omitempty" jsonschema:"Hash the files under working_dir (skipping .git, up to 10000 files) before and after each run and report the created, modified and deleted paths in file_changes"`
+
+	Hermetic    bool     `json:"hermetic,omitempty" jsonschema:"Best effort offline runs: set environment variables that ask common toolchains (Go, npm, yarn, pip, uv, cargo) not to download anything, and warn when the command looks network-dependent. Network access is not blocked"`
+	HermeticEnv []string `json:"hermetic_env,omitempty" jsonschema:"Optional KEY=VALUE entries that replace the default hermetic variables, e.g. [\"GOPROXY=off\"]; env still takes precedence"`
+
+	SummaryCommand []string `json:"summary_command,omitempty" jsonschema:"Optional command, e.g. [\"python\",\"scripts/summarize.py\"], that receives a finished run's stdout then stderr on stdin and prints a short summary, returned as summary; it runs in working_dir with a 10 second timeout, and run_tests falls back to its own summary if it fails"`
+
+	VerboseArgs []string `json:"verbose_args,omitempty" jsonschema:"Optional arguments that run_tests with verbose appends to the command, e.g. [\"-v\"]"`
+	RaceArgs    []string `json:"race_args,omitempty" jsonschema:"Optional arguments that run_tests with race appends to the command to turn on a race detector or sanitizer, e.g. [\"-race\"] for go test"`
+
+	FailFastFlag string `json:"fail_fast_flag,omitempty" jsonschema:"Optional flag that makes the runner stop at the first failing test, e.g. -failfast for go test or --bail for jest; run_tests with fail_fast appends it"`
+
+	RunAsUser  string `json:"run_as_user,omitempty" jsonschema:"Optional user, by name or numeric ID, to run the command as instead of the verifier's own user (Unix only; the verifier must run as root to switch users)"`
+	RunAsGroup string `json:"run_as_group,omitempty" jsonschema:"Optional group, by name or numeric ID, to run the command as (default: run_as_user's groups)"`
+
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty" jsonschema:"Optional factor, greater than 1, that turns on anomaly detection: run_tests flags a run whose duration or output size is this many times above or below the median of the config's recent successful runs (e.g. 3)"`
+}
+
+type registerResult struct {
+	ConfigPath string   `json:"config_path"`
+	Command    []string `json:"command"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	Env        []string `json:"env,omitempty"`
+	Nice       int      `json:"nice,omitempty"`
+	UpdatedAt  string   `json:"updated_at"`
+	Message    string   `json:"message"`
+
+	ExitCodeMessages map[string]string `json:"exit_code_messages,omitempty"`
+	SuccessExitCodes []int             `json:"success_exit_codes,omitempty"`
+	Container        *containerConfig  `json:"container,omitempty"`
+	FailureMarkers   []string          `json:"failure_markers,omitempty"`
+
+	OutputEncoding    string `json:"output_encoding,omitempty"`
+	NormalizeNewlines bool   `json:"normalize_newlines,omitempty"`
+	OutputFormat      string `json:"output_format,omitempty"`
+
+	OutputFilters []filterRule `json:"output_filters,omitempty"`
+	CacheSources  []string     `json:"cache_sources,omitempty"`
+
+	InheritEnv     string   `json:"inherit_env,omitempty"`
+	InheritEnvKeys []string `json:"inherit_env_keys,omitempty"`
+
+	FailOnOutputPatterns []string `json:"fail_on_output_patterns,omitempty"`
+	NoTestsPatterns      []string `json:"no_tests_patterns,omitempty"`
+	FailOnNoTests        bool     `json:"fail_on_no_tests,omitempty"`
+	ArgsFromFile         string   `json:"args_from_file,omitempty"`
+
+	ExpandGlobs        bool `json:"expand_globs,omitempty"`
+	FailUnmatchedGlobs bool `json:"fail_unmatched_globs,omitempty"`
+
+	TemplateArgs           bool `json:"template_args,omitempty"`
+	AllowUnsetTemplateVars bool `json:"allow_unset_template_vars,omitempty"`
+
+	GitHubReport *githubReportConfig `json:"github_report,omitempty"`
+	SmokeCommand []string            `json:"smoke_command,omitempty"`
+	KillLadder   []killStep          `json:"kill_ladder,omitempty"`
+	EnsureDirs   []string            `json:"ensure_dirs,omitempty"`
+
+	TrackFileChanges bool `json:"track_file_changes,omitempty"`
+
+	Hermetic    bool     `json:"hermetic,omitempty"`
+	HermeticEnv []string `json:"hermetic_env,omitempty"`
+
+	SummaryCommand []string `json:"summary_command,omitempty"`
+
+	VerboseArgs []string `json:"verbose_args,omitempty"`
+	RaceArgs    []string `json:"race_args,omitempty"`
+
+	FailFastFlag string `json:"fail_fast_flag,omitempty"`
+
+	RunAsUser  string `json:"run_as_user,omitempty"`
+	RunAsGroup string `json:"run_as_group,omitempty"`
+
+	AnomalyFactor float64 `json:"anomaly_factor,omitempty"`
+
+	Fingerprint string `json:"fingerprint"`
+}
+
+type registerAndRunArgs struct {
+	Register registerArgs `json:"register" jsonschema:"Test command to register, as accepted by register_test_command"`
+	Run      runArgs      `json:"run,omitempty" jsonschema:"Options for the run that follows registration, as accepted by run_tests"`
+}
+
+type registerAndRunResult struct {
+	Register registerResult `json:"register"`
+	Run      runResult      `json:"run"`
+}
+
+func registerRegisterAndRunTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolRegisterAndRun,
+		Description: "Register the test command (written to the shared config file, exactly like register_test_command) and immediately run it. Nothing is run if the registration is invalid.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args registerAndRunArgs) (*mcp.CallToolResult, registerAndRunResult, error) {
+		if stdinConfig != nil {
+			return nil, registerAndRunResult{}, fmt.Errorf("config was read from stdin at startup; registering a new command requires restarting the server without -config-stdin")
+		}
+
+		cfg, warnings, err := newStoredConfig(args.Register)
+		if err != nil {
+			return nil, registerAndRunResult{}, err
+		}
+
+		cfgPath, err := configPath(args.Register.ConfigPath)
+		if err != nil {
+			return nil, registerAndRunResult{}, err
+		}
+
+		if err := checkRegistrationRate(); err != nil {
+			return nil, registerAndRunResult{}, err
+		}
+		if err := writeConfig(cfgPath, cfg); err != nil {
+			return nil, registerAndRunResult{}, err
+		}
+		fingerprint, err := configFingerprint(cfg)
+		if err != nil {
+			return nil, registerAndRunResult{}, err
+		}
+		invalidateConfigCache()
+
+		registered := registerResult{
+			ConfigPath: cfgPath,
+			Command:    cfg.Command,
+			WorkingDir: cfg.WorkingDir,
+			Env:        cfg.Env,
+			Nice:       cfg.Nice,
+			UpdatedAt:  cfg.UpdatedAt,
+			Message:    "Test command registered.",
+
+			ExitCodeMessages: cfg.ExitCodeMessages,
+			SuccessExitCodes: cfg.SuccessExitCodes,
+			Container:        cfg.Container,
+			FailureMarkers:   cfg.FailureMarkers,
+
+			OutputEncoding:    cfg.OutputEncoding,
+			NormalizeNewlines: cfg.NormalizeNewlines,
+			OutputFormat:      cfg.OutputFormat,
+
+			OutputFilters: cfg.OutputFilters,
+			CacheSources:  cfg.CacheSources,
+
+			InheritEnv:     cfg.InheritEnv,
+			InheritEnvKeys: cfg.InheritEnvKeys,
+
+			FailOnOutputPatterns: cfg.FailOnOutputPatterns,
+			NoTestsPatterns:      cfg.NoTestsPatterns,
+			FailOnNoTests:        cfg.FailOnNoTests,
+			ArgsFromFile:         cfg.ArgsFromFile,
+
+			ExpandGlobs:        cfg.ExpandGlobs,
+			FailUnmatchedGlobs: cfg.FailUnmatchedGlobs,
+
+			TemplateArgs:           cfg.TemplateArgs,
+			AllowUnsetTemplateVars: cfg.AllowUnsetTemplateVars,
+
+			GitHubReport: cfg.GitHubReport,
+			SmokeCommand: cfg.SmokeCommand,
+			KillLadder:   cfg.KillLadder,
+			EnsureDirs:   cfg.EnsureDirs,
+
+			TrackFileChanges: cfg.TrackFileChanges,
+
+			Hermetic:    cfg.Hermetic,
+			HermeticEnv: cfg.HermeticEnv,
+
+			SummaryCommand: cfg.SummaryCommand,
+
+			VerboseArgs: cfg.VerboseArgs,
+			RaceArgs:    cfg.RaceArgs,
+
+			FailFastFlag: cfg.FailFastFlag,
+
+			RunAsUser:  cfg.RunAsUser,
+			RunAsGroup: cfg.RunAsGroup,
+
+			AnomalyFactor: cfg.AnomalyFactor,
+
+			Fingerprint: fingerprint,
+		}
+		for _, warning := range warnings {
+			registered.Message += " Warning: " + warning + "."
+		}
+
+		// Always run the config that was just written.
+		args.Run.ConfigPath = args.Register.ConfigPath
+		toolResult, ran, err := runTests(ctx, req, args.Run)
+		if err != nil {
+			return nil, registerAndRunResult{}, fmt.Errorf("test command registered, but the run failed: %w", err)
+		}
+
+		text := registered.Message
+		for _, content := range toolResult.Content {
+			if tc, ok := content.(*mcp.TextContent); ok {
+				text += " " + tc.Text
+			}
+		}
+		toolResult.Content = []mcp.Content{&mcp.TextContent{Text: text}}
+		return toolResult, registerAndRunResult{Register: registered, Run: ran}, nil
+	})
+}
+
+// newStoredConfig validates registration arguments and builds the config
+// that will be written for later runs, along with any warnings for the caller.
+func newStoredConfig(args registerArgs) (storedConfig, []string, error) {
+	command, err := validateCommand(args.Command)
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	var warnings []string
+	if tokens := shellTokens(command); len(tokens) > 0 {
+		if !args.AllowShellTokens {
+			return storedConfig{}, nil, fmt.Errorf("%s; set allow_shell_tokens to register it anyway", shellTokenWarning(tokens))
+		}
+		warnings = append(warnings, shellTokenWarning(tokens))
+	}
+
+	cfg, err := validateConfig(storedConfig{
+		Command:    command,
+		WorkingDir: args.WorkingDir,
+		Env:        args.Env,
+		Nice:       clampNice(args.Nice),
+
+		ExitCodeMessages: args.ExitCodeMessages,
+		SuccessExitCodes: args.SuccessExitCodes,
+		Container:        args.Container,
+		FailureMarkers:   args.FailureMarkers,
+
+		OutputEncoding:    args.OutputEncoding,
+		NormalizeNewlines: args.NormalizeNewlines,
+		OutputFormat:      args.OutputFormat,
+
+		OutputFilters: args.OutputFilters,
+		CacheSources:  args.CacheSources,
+
+		InheritEnv:     args.InheritEnv,
+		InheritEnvKeys: args.InheritEnvKeys,
+
+		FailOnOutputPatterns: args.FailOnOutputPatterns,
+		NoTestsPatterns:      args.NoTestsPatterns,
+		FailOnNoTests:        args.FailOnNoTests,
+		ArgsFromFile:         args.ArgsFromFile,
+
+		ExpandGlobs:        args.ExpandGlobs,
+		FailUnmatchedGlobs: args.FailUnmatchedGlobs,
+
+		TemplateArgs:           args.TemplateArgs,
+		AllowUnsetTemplateVars: args.AllowUnsetTemplateVars,
+
+		GitHubReport: args.GitHubReport,
+		SmokeCommand: args.SmokeCommand,
+		KillLadder:   args.KillLadder,
+		EnsureDirs:   args.EnsureDirs,
+
+		TrackFileChanges: args.TrackFileChanges,
+
+		Hermetic:    args.Hermetic,
+		HermeticEnv: args.HermeticEnv,
+
+		SummaryCommand: args.SummaryCommand,
+
+		VerboseArgs: args.VerboseArgs,
+		RaceArgs:    args.RaceArgs,
+
+		FailFastFlag: args.FailFastFlag,
+
+		RunAsUser:  args.RunAsUser,
+		RunAsGroup: args.RunAsGroup,
+
+		AnomalyFactor: args.AnomalyFactor,
+	})
+	if err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := checkCommandAllowed(cfg.Command); err != nil {
+		return storedConfig{}, nil, err
+	}
+	if err := checkCommandAllowed(cfg.SmokeCommand); err != nil {
+		return storedConfig{}, nil, fmt.Errorf("smoke_command: %w", err)
+	}
+	if err := checkCommandAllowed(cfg.SummaryCommand); err != nil {
+		return storedConfig{}, nil, fmt.Errorf("summary_command: %w", err)
+	}
+	cfg.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	return cfg, warnings, nil
+}
+
+// shellOperators are tokens that only have meaning to a shell. In argv mode
+// they reach the program as literal arguments.
+var shellOperators = map[string]bool{
+	"&&": true, "||": true, "|": true, ";": true, "&": true,
+	">": true, ">>": true, "<": true, "2>": true, "2>&1": true,
+}
+
+// shellTokens returns the command entries that look like shell operators.
+func shellTokens(command []string) []string {
+	var found []string
+	for _, part := range command {
+		if shellOperators[part] {
+			found = append(found, part)
+		}
+	}
+	return found
+}
+
+func shellTokenWarning(tokens []string) string {
+	return fmt.Sprintf("command contains shell operators %q that are passed to the program literally, not interpreted; wrap it in a shell instead, e.g. [\"sh\",\"-c\",\"npm test && npm run lint\"] (or [\"cmd\",\"/c\",...] on Windows)", tokens)
+}
+
+func writeConfig(path string, cfg storedConfig) error {
+	data, err := marshalConfig(path, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	fileMode, dirMode, err := configModes()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, fileMode); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write temp config in %s: %w", dir, err)
+	}
+	// WriteFile's mode is filtered by the umask and ignored for an existing
+	// file, so set it explicitly.
+	if err := os.Chmod(tmp, fileMode); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to set config file mode: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		// Some platforms refuse to rename over an existing file, so replace
+		// it explicitly and report whichever attempt failed last.
+		if _, statErr := os.Stat(path); statErr == nil {
+			if rmErr := os.Remove(path); rmErr == nil {
+				err = os.Rename(tmp, path)
+			}
+		}
+		if err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("failed to move config into place at %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errRunAsUnsupported = errors.New("run_as_user and run_as_group are only supported on Unix")
+
+type runAs struct{}
+
+func resolveRunAs(userName, groupName string) (*runAs, error) {
+	return nil, errRunAsUnsupported
+}
+
+func (r *runAs) apply(cmd *exec.Cmd) {}
+
+func (r *runAs) dropsRoot() bool {
+	return false
+}
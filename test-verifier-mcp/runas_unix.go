@@ -0,0 +1,90 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// runAs is the identity a config's command runs as.
+type runAs struct {
+	cred *syscall.Credential // nil when the verifier already is that identity
+	uid  uint32
+}
+
+// resolveRunAs builds the credential for run_as_user and run_as_group.
+// Without run_as_group the user's primary and supplementary groups are
+// used; without run_as_user the verifier's own user is kept. Switching to
+// another identity needs the verifier to run as root.
+func resolveRunAs(userName, groupName string) (*runAs, error) {
+	u, g, err := lookupRunAs(userName, groupName)
+	if err != nil {
+		return nil, err
+	}
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	if u != nil {
+		if cred.Uid, err = parseID(u.Uid); err != nil {
+			return nil, fmt.Errorf("run_as_user %q: %w", userName, err)
+		}
+		if cred.Gid, err = parseID(u.Gid); err != nil {
+			return nil, fmt.Errorf("run_as_user %q: %w", userName, err)
+		}
+		if g == nil {
+			groupIDs, err := u.GroupIds()
+			if err != nil {
+				return nil, fmt.Errorf("run_as_user %q: failed to list groups: %w", userName, err)
+			}
+			for _, id := range groupIDs {
+				if gid, err := parseID(id); err == nil {
+					cred.Groups = append(cred.Groups, gid)
+				}
+			}
+		}
+	}
+	if g != nil {
+		if cred.Gid, err = parseID(g.Gid); err != nil {
+			return nil, fmt.Errorf("run_as_group %q: %w", groupName, err)
+		}
+		cred.Groups = []uint32{cred.Gid}
+	}
+
+	euid, egid := uint32(os.Geteuid()), uint32(os.Getegid())
+	if euid != 0 {
+		if cred.Uid == euid && cred.Gid == egid {
+			return &runAs{uid: euid}, nil
+		}
+		return nil, fmt.Errorf("run_as_user and run_as_group need the verifier to run as root to switch to uid %d, gid %d; it runs as uid %d", cred.Uid, cred.Gid, euid)
+	}
+	return &runAs{cred: cred, uid: cred.Uid}, nil
+}
+
+// apply makes cmd start as the resolved identity.
+func (r *runAs) apply(cmd *exec.Cmd) {
+	if r == nil || r.cred == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = r.cred
+}
+
+// dropsRoot reports whether the command runs as a user other than root.
+func (r *runAs) dropsRoot() bool {
+	return r != nil && r.uid != 0
+}
+
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid numeric ID %q", id)
+	}
+	return uint32(n), nil
+}
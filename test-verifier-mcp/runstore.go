@@ -0,0 +1,252 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolClearHistory = "clear_history"
+
+	runOutputURIPrefix   = "test-verifier://runs/"
+	runOutputURITemplate = runOutputURIPrefix + "{id}/{stream}"
+
+	// maxStoredRuns caps how many runs' output is kept for resource reads;
+	// the oldest run is dropped first.
+	maxStoredRuns = 32
+)
+
+type storedOutput struct {
+	stdout string
+	stderr string
+
+	// stdoutFile and stderrFile hold the full output of spilled streams,
+	// read back with the run's output encoding and passed through filter,
+	// the config's output_filters.
+	stdoutFile        string
+	stderrFile        string
+	encoding          string
+	normalizeNewlines bool
+	filter            func(string) string
+}
+
+// removeFiles deletes the spill files of a run that left the store.
+func (o storedOutput) removeFiles() {
+	for _, name := range []string{o.stdoutFile, o.stderrFile} {
+		if name != "" {
+			os.Remove(name)
+		}
+	}
+}
+
+// text returns the stored output of stream, reading a spilled stream from
+// its file.
+func (o storedOutput) text(stream string) (string, error) {
+	text, file := o.stdout, o.stdoutFile
+	if stream == "stderr" {
+		text, file = o.stderr, o.stderrFile
+	}
+	if file == "" {
+		return text, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	text = decodeOutput(data, o.encoding, o.normalizeNewlines)
+	if o.filter != nil {
+		text = o.filter(text)
+	}
+	return text, nil
+}
+
+// runOutputs keeps the output of runs made with output_as_links, in memory,
+// so clients can read it through the runs resource.
+var runOutputs struct {
+	mu    sync.Mutex
+	order []string
+	byID  map[string]storedOutput
+}
+
+func newRunID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func storeRunOutput(id string, output storedOutput) {
+	runOutputs.mu.Lock()
+	defer runOutputs.mu.Unlock()
+	if runOutputs.byID == nil {
+		runOutputs.byID = make(map[string]storedOutput)
+	}
+	if previous, ok := runOutputs.byID[id]; ok {
+		previous.removeFiles()
+		// A caller-provided trace ID may be reused; keep only the latest run.
+		for i, stored := range runOutputs.order {
+			if stored == id {
+				runOutputs.order = append(runOutputs.order[:i:i], runOutputs.order[i+1:]...)
+				break
+			}
+		}
+	}
+	if len(runOutputs.order) >= maxStoredRuns {
+		runOutputs.byID[runOutputs.order[0]].removeFiles()
+		delete(runOutputs.byID, runOutputs.order[0])
+		runOutputs.order = runOutputs.order[1:]
+	}
+	runOutputs.order = append(runOutputs.order, id)
+	runOutputs.byID[id] = output
+}
+
+// hasRunOutput reports whether the output of run id is still in the store.
+func hasRunOutput(id string) bool {
+	runOutputs.mu.Lock()
+	defer runOutputs.mu.Unlock()
+	_, ok := runOutputs.byID[id]
+	return ok
+}
+
+// clearRunOutputs drops all but the keepLast most recent runs' output and
+// returns how many runs it removed, or would remove when dryRun is set.
+func clearRunOutputs(keepLast int, dryRun bool) int {
+	runOutputs.mu.Lock()
+	defer runOutputs.mu.Unlock()
+	if len(runOutputs.order) <= keepLast {
+		return 0
+	}
+	stale := runOutputs.order[:len(runOutputs.order)-keepLast]
+	removed := len(stale)
+	if !dryRun {
+		for _, id := range stale {
+			runOutputs.byID[id].removeFiles()
+			delete(runOutputs.byID, id)
+		}
+		runOutputs.order = append([]string(nil), runOutputs.order[removed:]...)
+	}
+	return removed
+}
+
+func runOutputURI(id, stream string) string {
+	return runOutputURIPrefix + id + "/" + stream
+}
+
+// linkOutput moves a finished run's output into the run store, under its
+// run ID, and replaces it in the result with resource URIs. A run with
+// spilled output is already in the store.
+func linkOutput(result *runResult) []mcp.Content {
+	if result.RunID == "" {
+		result.RunID = newRunID()
+	}
+	if result.StdoutFile == "" && result.StderrFile == "" {
+		storeRunOutput(result.RunID, storedOutput{stdout: result.Stdout, stderr: result.Stderr})
+	}
+
+	var links []mcp.Content
+	for _, stream := range []struct {
+		name string
+		text *string
+		uri  *string
+		file string
+	}{
+		{"stdout", &result.Stdout, &result.StdoutURI, result.StdoutFile},
+		{"stderr", &result.Stderr, &result.StderrURI, result.StderrFile},
+	} {
+		*stream.uri = runOutputURI(result.RunID, stream.name)
+		size := int64(len(*stream.text))
+		if info, err := os.Stat(stream.file); stream.file != "" && err == nil {
+			size = info.Size()
+		}
+		links = append(links, &mcp.ResourceLink{
+			URI:      *stream.uri,
+			Name:     stream.name,
+			Title:    fmt.Sprintf("%s of run %s", stream.name, result.RunID),
+			MIMEType: "text/plain",
+			Size:     &size,
+		})
+		*stream.text = ""
+	}
+	return links
+}
+
+func registerRunOutputResource(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "run-output",
+		Title:       "Test run output",
+		Description: "Captured stdout or stderr of a run_tests call made with output_as_links, or whose output was spilled to disk. Only the most recent runs are kept.",
+		MIMEType:    "text/plain",
+		URITemplate: runOutputURITemplate,
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		id, stream, ok := strings.Cut(strings.TrimPrefix(uri, runOutputURIPrefix), "/")
+		if !ok || !strings.HasPrefix(uri, runOutputURIPrefix) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+
+		runOutputs.mu.Lock()
+		output, found := runOutputs.byID[id]
+		runOutputs.mu.Unlock()
+		if !found {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+
+		if stream != "stdout" && stream != "stderr" {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		text, err := output.text(stream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s of run %s: %w", stream, id, err)
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "text/plain", Text: text}}}, nil
+	})
+}
+
+type clearHistoryArgs struct {
+	KeepLast   int  `json:"keep_last,omitempty" jsonschema:"Keep this many of the most recent entries (default 0: remove all)"`
+	ClearCache bool `json:"clear_cache,omitempty" jsonschema:"Also clear cached successful results"`
+	DryRun     bool `json:"dry_run,omitempty" jsonschema:"Only report how many entries would be removed"`
+}
+
+type clearHistoryResult struct {
+	RunsRemoved         int  `json:"runs_removed"`
+	SummariesRemoved    int  `json:"summaries_removed"`
+	CacheEntriesRemoved int  `json:"cache_entries_removed"`
+	DryRun              bool `json:"dry_run,omitempty"`
+}
+
+func registerClearHistoryTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        toolClearHistory,
+		Description: "Remove stored run output (served by the test-verifier://runs resource), the run summaries included in export_bundle and, with clear_cache, cached successful results, optionally keeping the most recent entries. Use dry_run to preview.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args clearHistoryArgs) (*mcp.CallToolResult, clearHistoryResult, error) {
+		if args.KeepLast < 0 {
+			return nil, clearHistoryResult{}, fmt.Errorf("keep_last must not be negative")
+		}
+		result := clearHistoryResult{
+			RunsRemoved:      clearRunOutputs(args.KeepLast, args.DryRun),
+			SummariesRemoved: clearRunHistory(args.KeepLast, args.DryRun),
+			DryRun:           args.DryRun,
+		}
+		if args.ClearCache {
+			result.CacheEntriesRemoved = clearCachedResults(args.KeepLast, args.DryRun)
+		}
+		verb := "Removed"
+		if args.DryRun {
+			verb = "Would remove"
+		}
+		message := fmt.Sprintf("%s output of %d runs, %d run summaries and %d cached results.", verb, result.RunsRemoved, result.SummariesRemoved, result.CacheEntriesRemoved)
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: message}}}, result, nil
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoredOutputFiltersSpilledStreams(t *testing.T) {
+	cfg, err := validateConfig(storedConfig{
+		Command:       []string{"go", "test"},
+		OutputFilters: []filterRule{{Pattern: `token-\d+`, Replacement: "[redacted]"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "stdout.log")
+	if err := os.WriteFile(file, []byte("using token-1234\r\nok\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output := storedOutput{stdoutFile: file, normalizeNewlines: true, filter: cfg.filterOutput}
+
+	got, err := output.text("stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "using [redacted]\nok\n"; got != want {
+		t.Errorf("spilled stdout = %q, want %q", got, want)
+	}
+}
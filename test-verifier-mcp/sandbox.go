@@ -0,0 +1,182 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const allowedRootsEnvVar = "TEST_VERIFIER_ALLOWED_ROOTS"
+
+// allowedRoots returns the symlink-resolved roots listed in
+// TEST_VERIFIER_ALLOWED_ROOTS (separated like PATH: ':' on Unix, ';' on
+// Windows). A nil result means the sandbox is disabled.
+func allowedRoots() ([]string, error) {
+	raw := strings.TrimSpace(os.Getenv(allowedRootsEnvVar))
+	if raw == "" {
+		return nil, nil
+	}
+	var roots []string
+	for _, entry := range filepath.SplitList(raw) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resolved, err := resolvePath(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", allowedRootsEnvVar, entry, err)
+		}
+		roots = append(roots, resolved)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%s is set but lists no directories", allowedRootsEnvVar)
+	}
+	return roots, nil
+}
+
+// checkAllowedDir rejects dir unless it resolves to a location under one of
+// the allowed roots. An empty dir means the server's own working directory.
+func checkAllowedDir(dir string) error {
+	roots, err := allowedRoots()
+	if err != nil || roots == nil {
+		return err
+	}
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	resolved, err := resolvePath(dir)
+	if err != nil {
+		return fmt.Errorf("cannot resolve working_dir %q: %w", dir, err)
+	}
+	for _, root := range roots {
+		if isWithin(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: working_dir %q is outside the directories allowed by %s", os.ErrPermission, resolved, allowedRootsEnvVar)
+}
+
+// checkAllowedMounts rejects container bind mounts whose host source is
+// outside the allowed roots, so a config cannot mount arbitrary host paths
+// into its container. Relative sources resolve against workingDir, and a
+// source that does not exist yet, which docker would create, is checked by
+// its nearest existing ancestor.
+func checkAllowedMounts(c *containerConfig, workingDir string) error {
+	if c == nil || len(c.Mounts) == 0 {
+		return nil
+	}
+	roots, err := allowedRoots()
+	if err != nil || roots == nil {
+		return err
+	}
+	for _, mount := range c.Mounts {
+		source, ok := mountSource(mount)
+		if !ok {
+			continue
+		}
+		if !filepath.IsAbs(source) && workingDir != "" {
+			source = filepath.Join(workingDir, source)
+		}
+		resolved, err := resolveExisting(source)
+		if err != nil {
+			return fmt.Errorf("cannot resolve container mount %q: %w", mount, err)
+		}
+		allowed := false
+		for _, root := range roots {
+			allowed = allowed || isWithin(root, resolved)
+		}
+		if !allowed {
+			return fmt.Errorf("%w: container mount source %q is outside the directories allowed by %s", os.ErrPermission, resolved, allowedRootsEnvVar)
+		}
+	}
+	return nil
+}
+
+// checkAllowedArgsFile rejects an args_from_file manifest that does not
+// resolve to a location under one of the allowed roots, so a config cannot
+// read arguments from arbitrary files on the host.
+func checkAllowedArgsFile(path string) error {
+	roots, err := allowedRoots()
+	if err != nil || roots == nil {
+		return err
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return fmt.Errorf("cannot resolve args_from_file %q: %w", path, err)
+	}
+	for _, root := range roots {
+		if isWithin(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: args_from_file %q is outside the directories allowed by %s", os.ErrPermission, resolved, allowedRootsEnvVar)
+}
+
+// checkAllowedConfigPath rejects a config file outside the allowed roots.
+// The file and its directory may not exist yet, so the nearest existing
+// ancestor is resolved instead.
+func checkAllowedConfigPath(path string) error {
+	roots, err := allowedRoots()
+	if err != nil || roots == nil {
+		return err
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return fmt.Errorf("cannot resolve config_path %q: %w", path, err)
+	}
+	for _, root := range roots {
+		if isWithin(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: config_path %q is outside the directories allowed by %s", os.ErrPermission, resolved, allowedRootsEnvVar)
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path
+// and appends the components that do not exist yet.
+func resolveExisting(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(abs)}, missing...)
+		abs = parent
+	}
+}
+
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,93 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const phaseSmoke = "smoke"
+
+// smokeResult summarizes the smoke phase of a smoke_first run.
+type smokeResult struct {
+	Command    []string `json:"command"`
+	ExitCode   int      `json:"exit_code"`
+	Success    bool     `json:"success"`
+	TimedOut   bool     `json:"timed_out,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// runSmokeFirst runs the config's smoke command and, only if it passes, the
+// full command. A failed smoke run is returned in place of the full run,
+// with GatingPhase set.
+func runSmokeFirst(ctx context.Context, req *mcp.CallToolRequest, cfg storedConfig, cfgPath string, cached bool, args runArgs, warnings []string) (*mcp.CallToolResult, runResult, error) {
+	if len(cfg.SmokeCommand) == 0 {
+		return nil, runResult{}, errors.New("smoke_first needs a smoke_command in the registered config")
+	}
+	fingerprint, err := configFingerprint(cfg)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+
+	// The smoke phase runs the smoke command as is: no manifest arguments,
+	// extra_args, toggles, expectation, GitHub status or captures.
+	smokeCfg := cfg
+	smokeCfg.Command, smokeCfg.ArgsFromFile = cfg.SmokeCommand, ""
+	smokeArgs := args
+	smokeArgs.SmokeFirst, smokeArgs.ExtraArgs, smokeArgs.ExpectExitCode, smokeArgs.ReportToGitHub = false, nil, nil, false
+	smokeArgs.CaptureVars, smokeArgs.Verbose, smokeArgs.Race, smokeArgs.FailFast = nil, false, false, false
+	toolResult, smoke, err := runConfig(ctx, req, smokeCfg, cfgPath, cached, smokeArgs, warnings)
+	if err != nil {
+		return nil, runResult{}, fmt.Errorf("smoke phase: %w", err)
+	}
+	if smoke.OverallTimedOut {
+		// Whatever step of the smoke phase was running, the full suite
+		// has no time left.
+		smoke.DeadlinePhase = phaseSmoke
+		smoke.GatingPhase = phaseSmoke
+		smoke.ConfigFingerprint = fingerprint
+		prefixSummary(toolResult, "Overall timeout ran out in the smoke phase; the full suite was not run. ")
+		toolResult.IsError = true
+		return toolResult, smoke, nil
+	}
+	if !smoke.Success {
+		smoke.GatingPhase = phaseSmoke
+		smoke.ConfigFingerprint = fingerprint
+		prefixSummary(toolResult, "Smoke test failed; the full suite was not run. ")
+		toolResult.IsError = true
+		return toolResult, smoke, nil
+	}
+
+	fullArgs := args
+	fullArgs.SmokeFirst = false
+	toolResult, result, err := runConfig(ctx, req, cfg, cfgPath, cached, fullArgs, warnings)
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	result.Smoke = &smokeResult{
+		Command:    smoke.Command,
+		ExitCode:   smoke.ExitCode,
+		Success:    smoke.Success,
+		TimedOut:   smoke.TimedOut,
+		DurationMs: smoke.DurationMs,
+		Error:      smoke.Error,
+	}
+	prefixSummary(toolResult, fmt.Sprintf("Smoke test passed in %dms. ", smoke.DurationMs))
+	return toolResult, result, nil
+}
+
+// prefixSummary puts prefix in front of the text summary of a run.
+func prefixSummary(toolResult *mcp.CallToolResult, prefix string) {
+	if len(toolResult.Content) == 0 {
+		return
+	}
+	if text, ok := toolResult.Content[0].(*mcp.TextContent); ok {
+		text.Text = prefix + text.Text
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	spillThresholdEnvVar  = "TEST_VERIFIER_SPILL_THRESHOLD"
+	defaultSpillThreshold = 16 << 20
+
+	// spillWindow is how much of the start and of the end of a spilled
+	// stream is kept in memory for the inline result.
+	spillWindow = 256 << 10
+)
+
+// spillThreshold is the output size, in bytes, past which a stream is
+// written to a temporary file instead of memory (-spill-threshold). Zero
+// only spills runs made with spill_output.
+var spillThreshold = defaultSpillThreshold
+
+// spillDir holds the spill files of this server process, created on first
+// use.
+var spillDir struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+func spillDirPath() (string, error) {
+	spillDir.once.Do(func() {
+		spillDir.path, spillDir.err = os.MkdirTemp("", "test-verifier-output-")
+	})
+	return spillDir.path, spillDir.err
+}
+
+// removeSpillDir deletes the spill files when the server shuts down. A
+// spill directory is only created once a run spills.
+func removeSpillDir() {
+	spillDir.once.Do(func() {})
+	if spillDir.path != "" {
+		os.RemoveAll(spillDir.path)
+	}
+}
+
+// spillBuffer captures one output stream. It keeps the output in memory
+// until it grows past its threshold, then moves it to a temporary file and
+// from there on keeps only the first and last spillWindow bytes in memory.
+type spillBuffer struct {
+	stream    string
+	threshold int // spill once the output exceeds this; negative never spills
+
+	mem  bytes.Buffer // the whole output, or its head once spilled
+	tail []byte       // the latest output once spilled, at most 2*spillWindow
+	file *os.File
+	path string
+	size int64
+	err  error // the first error writing the spill file
+}
+
+// newSpillBuffer returns a buffer for stream that spills at once when force
+// is set and past spillThreshold otherwise.
+func newSpillBuffer(stream string, force bool) *spillBuffer {
+	b := &spillBuffer{stream: stream, threshold: -1}
+	switch {
+	case force:
+		b.threshold = 0
+	case spillThreshold > 0:
+		b.threshold = spillThreshold
+	}
+	return b
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	b.size += int64(len(p))
+	if b.file == nil {
+		if b.threshold < 0 || b.mem.Len()+len(p) <= b.threshold || !b.spill() {
+			return b.mem.Write(p)
+		}
+	}
+	if room := spillWindow - b.mem.Len(); room > 0 {
+		b.mem.Write(p[:min(room, len(p))])
+	}
+	if b.err == nil {
+		if _, err := b.file.Write(p); err != nil {
+			b.err = err
+		}
+	}
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > 2*spillWindow {
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-spillWindow:]...)
+	}
+	return len(p), nil
+}
+
+// spill moves the output so far to a new spill file. On failure the output
+// stays in memory for the rest of the run.
+func (b *spillBuffer) spill() bool {
+	dir, err := spillDirPath()
+	if err == nil {
+		b.file, err = os.CreateTemp(dir, b.stream+"-*.log")
+	}
+	if err == nil {
+		_, err = b.file.Write(b.mem.Bytes())
+	}
+	if err != nil {
+		if b.file != nil {
+			b.file.Close()
+			os.Remove(b.file.Name())
+			b.file = nil
+		}
+		b.err = err
+		b.threshold = -1
+		return false
+	}
+	b.path = b.file.Name()
+	b.tail = append(b.tail, b.mem.Bytes()[max(b.mem.Len()-spillWindow, 0):]...)
+	b.mem.Truncate(min(b.mem.Len(), spillWindow))
+	return true
+}
+
+// close closes the spill file, keeping it for the runs resource.
+func (b *spillBuffer) close() {
+	if b.file != nil {
+		if err := b.file.Close(); err != nil && b.err == nil {
+			b.err = err
+		}
+		b.file = nil
+	}
+}
+
+func (b *spillBuffer) spilled() bool {
+	return b.path != ""
+}
+
+// Bytes returns the captured output, or for a spilled stream its head and
+// tail, cut at line boundaries, around a note on what was left out.
+func (b *spillBuffer) Bytes() []byte {
+	if !b.spilled() {
+		return b.mem.Bytes()
+	}
+	head := b.mem.Bytes()
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	}
+	tail := b.tail[max(len(b.tail)-spillWindow, 0):]
+	switch gap := b.size - int64(len(head)+len(tail)); {
+	case gap < 0:
+		// The windows overlap: the output is short enough to return whole.
+		tail = tail[-gap:]
+	case gap > 0:
+		if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+			tail = tail[i+1:]
+		}
+	}
+	omitted := b.size - int64(len(head)+len(tail))
+	var out bytes.Buffer
+	out.Write(head)
+	if omitted > 0 {
+		fmt.Fprintf(&out, "[... %d bytes omitted; the full %s is served by the runs resource ...]\n", omitted, b.stream)
+	}
+	out.Write(tail)
+	return out.Bytes()
+}
+
+// warning describes a failure to spill the stream, or "".
+func (b *spillBuffer) warning() string {
+	switch {
+	case b.err == nil:
+		return ""
+	case b.spilled():
+		return fmt.Sprintf("%s spill file %s is incomplete: %v", b.stream, b.path, b.err)
+	}
+	return fmt.Sprintf("%s was kept in memory: could not create a spill file: %v", b.stream, b.err)
+}
+
+// spilledChecksWarning says which output checks of a run with spilled
+// output only saw the start and end of the spilled streams, or "".
+func spilledChecksWarning(cfg storedConfig, captures bool, stdout, stderr *spillBuffer) string {
+	if !stdout.spilled() && !stderr.spilled() {
+		return ""
+	}
+	var checks []string
+	if len(cfg.FailOnOutputPatterns) > 0 {
+		checks = append(checks, "fail_on_output_patterns")
+	}
+	checks = append(checks, "no-tests detection")
+	if captures {
+		checks = append(checks, "capture_vars")
+	}
+	if cfg.OutputFormat != "" {
+		checks = append(checks, "output_format "+cfg.OutputFormat)
+	}
+	if len(cfg.SummaryCommand) > 0 {
+		checks = append(checks, "summary_command")
+	}
+	list := checks[len(checks)-1]
+	if len(checks) > 1 {
+		list = strings.Join(checks[:len(checks)-1], ", ") + " and " + list
+	}
+	return fmt.Sprintf("output was spilled, so %s only saw the first and last %d KiB of each spilled stream", list, spillWindow>>10)
+}
+
+// storeSpilledOutput puts a run with spilled output in the run store right
+// away, whether or not it asked for output_as_links, since the windowed
+// Stdout and Stderr are all it returns inline. The spilled streams are read
+// back from their files.
+func storeSpilledOutput(result *runResult, cfg storedConfig, stdout, stderr *spillBuffer) {
+	for _, b := range []*spillBuffer{stdout, stderr} {
+		if warning := b.warning(); warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
+	if !stdout.spilled() && !stderr.spilled() {
+		return
+	}
+	result.StdoutFile, result.StderrFile = stdout.path, stderr.path
+	storeRunOutput(result.RunID, storedOutput{
+		stdout:     result.Stdout,
+		stderr:     result.Stderr,
+		stdoutFile: stdout.path,
+		stderrFile: stderr.path,
+
+		encoding:          cfg.OutputEncoding,
+		normalizeNewlines: cfg.NormalizeNewlines,
+		filter:            cfg.filterOutput,
+	})
+	result.StdoutURI = runOutputURI(result.RunID, "stdout")
+	result.StderrURI = runOutputURI(result.RunID, "stderr")
+}
@@ -0,0 +1,85 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// summaryCommandTimeout bounds how long a summary_command may run.
+	summaryCommandTimeout = 10 * time.Second
+	// maxSummaryLen caps the summary kept from a summary_command.
+	maxSummaryLen = 4 << 10
+)
+
+// runSummaryCommand feeds a finished run's stdout, then stderr, to the
+// config's summary_command and returns what it printed, trimmed. It runs in
+// the run's working directory, environment and run_as_user, on the host even
+// for container runs. Any failure is returned for the caller to fall back on.
+func runSummaryCommand(ctx context.Context, cfg storedConfig, env []string, stdout, stderr string) (string, error) {
+	if err := checkCommandAllowed(cfg.SummaryCommand); err != nil {
+		return "", err
+	}
+	executable, err := lookPathIn(cfg.SummaryCommand[0], cfg.WorkingDir, env)
+	if err != nil {
+		return "", err
+	}
+	if err := checkExecutableAllowed(executable); err != nil {
+		return "", err
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, summaryCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, executable, cfg.SummaryCommand[1:]...)
+	cmd.Args[0] = cfg.SummaryCommand[0]
+	cmd.Dir = cfg.WorkingDir
+	cmd.Env = env
+	if cfg.RunAsUser != "" || cfg.RunAsGroup != "" {
+		identity, err := resolveRunAs(cfg.RunAsUser, cfg.RunAsGroup)
+		if err != nil {
+			return "", err
+		}
+		identity.apply(cmd)
+	}
+	cmd.Stdin = strings.NewReader(stdout + stderr)
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if seconds, expired := overallTimedOut(ctx); expired {
+		return "", fmt.Errorf("stopped when the overall timeout of %d seconds ran out", seconds)
+	}
+	if errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("timed out after %s", summaryCommandTimeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, truncateText(strings.TrimSpace(string(exitErr.Stderr)), 200))
+		}
+		return "", err
+	}
+	summary := strings.TrimSpace(decodeOutput(out, cfg.OutputEncoding, true))
+	if summary == "" {
+		return "", errors.New("it printed nothing")
+	}
+	return truncateText(summary, maxSummaryLen), nil
+}
+
+// truncateText cuts s to at most limit bytes, at a rune boundary, marking
+// the cut.
+func truncateText(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + " [truncated]"
+}
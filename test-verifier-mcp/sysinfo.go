@@ -0,0 +1,30 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"os"
+	"runtime"
+)
+
+// systemInfo describes the machine a run executed on, so results from
+// different hosts can be told apart.
+type systemInfo struct {
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"go_version"`
+	NumCPU    int    `json:"num_cpu"`
+	Hostname  string `json:"hostname,omitempty"`
+}
+
+func collectSystemInfo() *systemInfo {
+	hostname, _ := os.Hostname()
+	return &systemInfo{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		NumCPU:    runtime.NumCPU(),
+		Hostname:  hostname,
+	}
+}
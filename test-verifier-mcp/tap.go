@@ -0,0 +1,210 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	outputFormatTAP = "tap"
+
+	failureKindTAP = "tap"
+
+	// maxReportedTests caps how many test cases a report lists; the
+	// counts always cover every test.
+	maxReportedTests = 1000
+	// maxTestDiagnostics caps the diagnostics kept for one test case.
+	maxTestDiagnostics = 4 << 10
+	// maxTAPPlan is the largest plan taken at its word; a larger one is
+	// ignored like any other malformed plan line.
+	maxTAPPlan = 1 << 24
+)
+
+const (
+	testPass = "pass"
+	testFail = "fail"
+	testSkip = "skip"
+	testTodo = "todo"
+)
+
+// testCase is one test reported by the command's structured output.
+type testCase struct {
+	Number int    `json:"number"`
+	Name   string `json:"name,omitempty"`
+	// Status is pass, fail, skip or todo. A TODO test never fails the run.
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+// testReport is what was parsed from the output of a config with an
+// output_format.
+type testReport struct {
+	Format string `json:"format"`
+	// Planned is the number of tests announced by the plan line, if any;
+	// Missing counts planned tests that never reported.
+	Planned *int `json:"planned,omitempty"`
+	Missing int  `json:"missing,omitempty"`
+	Passed  int  `json:"passed"`
+	Failed  int  `json:"failed"`
+	Skipped int  `json:"skipped,omitempty"`
+	Todo    int  `json:"todo,omitempty"`
+	// BailOut is the reason given by a "Bail out!" line.
+	BailOut string `json:"bail_out,omitempty"`
+	// Truncated is set when Tests lists only the first maxReportedTests.
+	Truncated bool       `json:"truncated,omitempty"`
+	Tests     []testCase `json:"tests,omitempty"`
+}
+
+var (
+	tapPlan    = regexp.MustCompile(`^1\.\.(\d+)(?:\s*#.*)?$`)
+	tapTest    = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?(.*)$`)
+	tapBailOut = regexp.MustCompile(`^Bail out!\s*(.*)$`)
+)
+
+func normalizeOutputFormat(name string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return "", nil
+	case outputFormatTAP:
+		return outputFormatTAP, nil
+	}
+	return "", fmt.Errorf("unsupported output_format %q (want tap)", name)
+}
+
+// parseTAP reads Test Anything Protocol output: the plan line, top-level ok
+// and not ok lines with their SKIP and TODO directives, "Bail out!", and
+// the "#" comments and YAML blocks that follow a test as its diagnostics.
+// Indented subtest lines are skipped; their parent reports the outcome.
+func parseTAP(output string) *testReport {
+	report := &testReport{Format: outputFormatTAP}
+	var tests []testCase
+	seen := map[int]bool{}
+	inYAML := false
+	attach := func(line string) {
+		if len(tests) == 0 {
+			return
+		}
+		last := &tests[len(tests)-1]
+		if len(last.Diagnostics)+len(line) < maxTestDiagnostics {
+			last.Diagnostics += line + "\n"
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if inYAML {
+			attach(line)
+			inYAML = trimmed != "..."
+			continue
+		}
+		if trimmed == "---" && line != trimmed {
+			attach(line)
+			inYAML = true
+			continue
+		}
+		if line != strings.TrimLeft(line, " \t") {
+			continue
+		}
+		if m := tapPlan.FindStringSubmatch(line); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n <= maxTAPPlan {
+				report.Planned = &n
+			}
+			continue
+		}
+		if m := tapBailOut.FindStringSubmatch(line); m != nil {
+			report.BailOut = strings.TrimSpace(m[1])
+			if report.BailOut == "" {
+				report.BailOut = "Bail out!"
+			}
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			attach(line)
+			continue
+		}
+		m := tapTest.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		tc := testCase{Number: len(tests) + 1, Status: testPass}
+		if m[2] != "" {
+			if n, err := strconv.Atoi(m[2]); err == nil {
+				tc.Number = n
+			}
+		}
+		desc, directive, _ := strings.Cut(m[3], "#")
+		tc.Name = strings.TrimSpace(desc)
+		if m[1] != "" {
+			tc.Status = testFail
+		}
+		directive = strings.TrimSpace(directive)
+		switch word, reason, _ := strings.Cut(directive, " "); {
+		case strings.HasPrefix(strings.ToLower(word), "skip"):
+			tc.Status, tc.Reason = testSkip, strings.TrimSpace(reason)
+		case strings.EqualFold(word, "todo"):
+			tc.Status, tc.Reason = testTodo, strings.TrimSpace(reason)
+		}
+		seen[tc.Number] = true
+		tests = append(tests, tc)
+	}
+
+	for _, tc := range tests {
+		switch tc.Status {
+		case testPass:
+			report.Passed++
+		case testFail:
+			report.Failed++
+		case testSkip:
+			report.Skipped++
+		case testTodo:
+			report.Todo++
+		}
+	}
+	if report.Planned != nil {
+		report.Missing = *report.Planned
+		for n := range seen {
+			if n >= 1 && n <= *report.Planned {
+				report.Missing--
+			}
+		}
+	}
+	if len(tests) > maxReportedTests {
+		tests, report.Truncated = tests[:maxReportedTests], true
+	}
+	report.Tests = tests
+	return report
+}
+
+// failed reports whether the parsed output shows a failure the exit code
+// may have missed.
+func (r *testReport) failed() bool {
+	return r.Failed > 0 || r.Missing > 0 || r.BailOut != ""
+}
+
+// describe summarizes why failed returned true, e.g. "2 failed tests".
+func (r *testReport) describe() string {
+	var parts []string
+	if r.Failed > 0 {
+		parts = append(parts, plural(r.Failed, "failed test"))
+	}
+	if r.Missing > 0 {
+		parts = append(parts, fmt.Sprintf("%s out of %d planned", plural(r.Missing, "missing test"), *r.Planned))
+	}
+	if r.BailOut != "" {
+		parts = append(parts, fmt.Sprintf("a bail out (%s)", r.BailOut))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,50 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import "testing"
+
+func TestParseTAPMissing(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		wantPlanned int // -1 when no plan is taken
+		wantMissing int
+	}{
+		{"all reported", "1..2\nok 1\nok 2\n", 2, 0},
+		{"one missing", "1..3\nok 1\nnot ok 3\n", 3, 1},
+		{"duplicates and out of range", "1..3\nok 1\nok 1\nok 7\nok 0\n", 3, 2},
+		{"unnumbered tests", "1..2\nok\nok\n", 2, 0},
+		{"huge plan", "1..16777216\nok 1\n", 1 << 24, 1<<24 - 1},
+		{"absurd plan ignored", "1..4000000000000\nok 1\n", -1, 0},
+		{"overflowing plan ignored", "1..99999999999999999999999\nok 1\n", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := parseTAP(tt.output)
+			switch {
+			case tt.wantPlanned < 0 && report.Planned != nil:
+				t.Errorf("planned = %d, want the plan ignored", *report.Planned)
+			case tt.wantPlanned >= 0 && (report.Planned == nil || *report.Planned != tt.wantPlanned):
+				t.Errorf("planned = %v, want %d", report.Planned, tt.wantPlanned)
+			}
+			if report.Missing != tt.wantMissing {
+				t.Errorf("missing = %d, want %d", report.Missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestParseTAPDescribe(t *testing.T) {
+	report := parseTAP("1..3\nok 1\nnot ok 2 - parses\n")
+	if !report.failed() {
+		t.Fatal("failed() = false, want true")
+	}
+	if got, want := report.describe(), "1 failed test, 1 missing test out of 3 planned"; got != want {
+		t.Errorf("describe() = %q, want %q", got, want)
+	}
+	if report.Tests[1].Name != "parses" {
+		t.Errorf("test name = %q, want %q", report.Tests[1].Name, "parses")
+	}
+}
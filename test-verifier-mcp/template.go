@@ -0,0 +1,38 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// templateVar matches ${NAME} references in command arguments, and $${ as
+// the escape for a literal ${.
+var templateVar = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandTemplates replaces ${NAME} in every argument with NAME's value in
+// env. Unlike a shell, bare $NAME is left alone. An unset variable is an
+// error unless allowUnset is true, in which case it expands to "".
+func expandTemplates(argv, env []string, allowUnset bool) ([]string, error) {
+	expanded := make([]string, len(argv))
+	for i, arg := range argv {
+		var missing string
+		expanded[i] = templateVar.ReplaceAllStringFunc(arg, func(ref string) string {
+			if ref == "$${" {
+				return "${"
+			}
+			name := ref[2 : len(ref)-1]
+			value, ok := envValue(env, name)
+			if !ok && missing == "" {
+				missing = name
+			}
+			return value
+		})
+		if missing != "" && !allowUnset {
+			return nil, fmt.Errorf("command argument %q references unset variable ${%s}; pass it in env or set allow_unset_template_vars", arg, missing)
+		}
+	}
+	return expanded, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import "fmt"
+
+// toggleArgs returns the registered arguments of the toggles, verbose, race
+// and fail_fast, that args turns on. Turning on a toggle the config does not
+// define is an error rather than a silent no-op.
+func toggleArgs(cfg storedConfig, args runArgs) ([]string, error) {
+	var failFast []string
+	if cfg.FailFastFlag != "" {
+		failFast = []string{cfg.FailFastFlag}
+	}
+	var toggled []string
+	for _, toggle := range []struct {
+		name  string
+		on    bool
+		field string
+		args  []string
+	}{
+		{"verbose", args.Verbose, "verbose_args", cfg.VerboseArgs},
+		{"race", args.Race, "race_args", cfg.RaceArgs},
+		{"fail_fast", args.FailFast, "fail_fast_flag", failFast},
+	} {
+		if !toggle.on {
+			continue
+		}
+		if len(toggle.args) == 0 {
+			return nil, fmt.Errorf("%s was requested, but the registered config has no %s; set it with register_test_command", toggle.name, toggle.field)
+		}
+		toggled = append(toggled, toggle.args...)
+	}
+	return toggled, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2026.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	traceEnvEnvVar     = "TEST_VERIFIER_TRACE_ENV"
+	defaultTraceEnvVar = "TEST_RUN_ID"
+	maxTraceIDLen      = 128
+)
+
+// traceEnvVar names the environment variable that carries a run's trace ID
+// into the command (-trace-env). Empty leaves the environment alone.
+var traceEnvVar = defaultTraceEnvVar
+
+// traceIDPattern keeps trace IDs safe to use in environment values, log
+// lines and run resource URIs.
+var traceIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// resolveTraceID returns the caller's trace ID, or a new one when it is empty.
+func resolveTraceID(id string) (string, error) {
+	if id == "" {
+		return newRunID(), nil
+	}
+	if len(id) > maxTraceIDLen {
+		return "", fmt.Errorf("trace_id must be at most %d characters", maxTraceIDLen)
+	}
+	if !traceIDPattern.MatchString(id) {
+		return "", fmt.Errorf("trace_id %q may only contain letters, digits, '.', '_', ':' and '-'", id)
+	}
+	return id, nil
+}
+
+// traceEnv is the environment entry that passes id to the command, if any.
+func traceEnv(id string) []string {
+	if traceEnvVar == "" {
+		return nil
+	}
+	return []string{traceEnvVar + "=" + id}
+}